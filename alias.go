@@ -0,0 +1,118 @@
+package zipkines
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// aliasInfo is what the alias cache knows about an alias.
+type aliasInfo struct {
+	// writeIndex is the index the writes to the alias go to, empty for read
+	// only aliases.
+	writeIndex string
+}
+
+type aliasesResponse map[string]struct {
+	Aliases map[string]struct {
+		IsWriteIndex *bool `json:"is_write_index"`
+	} `json:"aliases"`
+}
+
+// StartAliasCachePoller fetches the aliases of the cluster at the given base
+// URL, e.g. "http://es-01:9200", through `GET /_alias` once every interval
+// until the context is cancelled. Requests targeting a cached alias are tagged
+// with "es.alias" set to "write" for the index, create, update, delete and
+// bulk calls, plus "es.alias.write_index" with the concrete index the writes
+// go to if the alias has one, e.g. a rollover alias, or to "read" for the
+// other calls. The cache is kept as it was while the
+// poll fails, including when ES answers with a non successful status code.
+// The first poll happens before returning. Polls go straight to the parent
+// transport hence they are not traced, the credentials can be set through
+// WithPollRequestDecorator. It returns ErrInvalidPollInterval if the
+// interval is not positive.
+func (r *Transport) StartAliasCachePoller(ctx context.Context, url string, interval time.Duration, opts ...PollOpt) error {
+	p := newPoller(opts)
+	return startPolling(ctx, interval, func() {
+		r.pollAliases(ctx, p, url)
+	})
+}
+
+func (r *Transport) pollAliases(ctx context.Context, p *poller, url string) {
+	aliases, err := r.fetchAliases(ctx, p, url)
+	if err != nil {
+		r.logger.Printf("failed to poll the aliases: %v", err)
+		return
+	}
+	r.aliases.Store(aliases)
+}
+
+func (r *Transport) fetchAliases(ctx context.Context, p *poller, url string) (map[string]aliasInfo, error) {
+	body, err := p.fetch(ctx, r.parent, url+"/_alias", 16<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	indices := aliasesResponse{}
+	if err := json.Unmarshal(body, &indices); err != nil {
+		return nil, err
+	}
+
+	aliases := map[string]aliasInfo{}
+	members := map[string][]string{}
+	for index, entry := range indices {
+		for alias, props := range entry.Aliases {
+			members[alias] = append(members[alias], index)
+			info := aliases[alias]
+			if props.IsWriteIndex != nil && *props.IsWriteIndex {
+				info.writeIndex = index
+			}
+			aliases[alias] = info
+		}
+	}
+
+	for alias, aliased := range members {
+		// ES writes to the only index of an alias unless it is explicitly
+		// flagged as not being the write index.
+		flag := indices[aliased[0]].Aliases[alias].IsWriteIndex
+		if len(aliased) == 1 && flag == nil {
+			aliases[alias] = aliasInfo{writeIndex: aliased[0]}
+		}
+	}
+	return aliases, nil
+}
+
+// aliasWriteOperations are the operations writing through an alias, hence
+// to its write index.
+var aliasWriteOperations = map[string]bool{
+	"index":  true,
+	"create": true,
+	"update": true,
+	"delete": true,
+	"bulk":   true,
+}
+
+// tagAlias tags whether the request targets an alias to write, along with
+// the write index it resolves to, or to read.
+func (r *Transport) tagAlias(span zipkin.Span, path string, operation string) {
+	aliases, _ := r.aliases.Load().(map[string]aliasInfo)
+	if aliases == nil {
+		return
+	}
+
+	info, ok := aliases[indexFromPath(path)]
+	if !ok {
+		return
+	}
+
+	if !aliasWriteOperations[operation] {
+		span.Tag("es.alias", "read")
+		return
+	}
+	span.Tag("es.alias", "write")
+	if info.writeIndex != "" {
+		span.Tag("es.alias.write_index", info.writeIndex)
+	}
+}
@@ -0,0 +1,97 @@
+package zipkines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestAliasCachePoller(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/_alias" {
+			if req.Header.Get("Authorization") != "ApiKey secret" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				rw.Write([]byte(`{"logs-000001": {"aliases": {"logs": {}}}}`))
+				return
+			}
+			rw.Write([]byte(`{
+				"logs-000001": {"aliases": {"logs": {"is_write_index": false}, "logs-read": {}}},
+				"logs-000002": {"aliases": {"logs": {"is_write_index": true}, "logs-read": {}}},
+				"users-v2": {"aliases": {"users": {}}}
+			}`))
+			return
+		}
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithLogger(discardLogger))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if want, have := ErrInvalidPollInterval, transport.StartAliasCachePoller(ctx, srv.URL, -time.Second); want != have {
+		t.Errorf("unexpected error for a negative interval; want %v, have %v", want, have)
+	}
+
+	// the unauthorized response is not cached
+	if err := transport.StartAliasCachePoller(ctx, srv.URL, time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if aliases, _ := transport.aliases.Load().(map[string]aliasInfo); aliases != nil {
+		t.Errorf("unexpected aliases cached from an unauthorized response: %v", aliases)
+	}
+
+	err = transport.StartAliasCachePoller(ctx, srv.URL, time.Hour, WithPollRequestDecorator(func(req *http.Request) {
+		req.Header.Set("Authorization", "ApiKey secret")
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		method     string
+		path       string
+		alias      string
+		writeIndex string
+	}{
+		{"POST", "/logs/_doc", "write", "logs-000002"},
+		{"GET", "/logs/_search", "read", ""},
+		{"GET", "/logs-read/_search", "read", ""},
+		{"POST", "/logs-read/_doc", "write", ""},
+		{"PUT", "/users/_doc/1", "write", "users-v2"},
+		{"GET", "/users/_doc/1", "read", ""},
+		{"POST", "/users/_bulk", "write", "users-v2"},
+		{"GET", "/logs-000001/_search", "", ""},
+	}
+
+	for _, tc := range testCases {
+		req, _ := http.NewRequest(tc.method, srv.URL+tc.path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		spans := reporter.Flush()
+		if want, have := 1, len(spans); want != have {
+			t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+		}
+
+		if want, have := tc.alias, spans[0].Tags["es.alias"]; want != have {
+			t.Errorf("unexpected alias kind for %s %s; want %q, have %q", tc.method, tc.path, want, have)
+		}
+
+		if want, have := tc.writeIndex, spans[0].Tags["es.alias.write_index"]; want != have {
+			t.Errorf("unexpected write index for %s %s; want %q, have %q", tc.method, tc.path, want, have)
+		}
+	}
+}
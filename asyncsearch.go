@@ -0,0 +1,64 @@
+package zipkines
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// asyncSearchAction returns the action of an async search endpoint, i.e.
+// "submit", "get", "status" or "delete", or false if it is not one.
+func asyncSearchAction(m EndpointMatch) (string, bool) {
+	if !strings.HasPrefix(m.Operation, "async_search.") {
+		return "", false
+	}
+	return strings.TrimPrefix(m.Operation, "async_search."), true
+}
+
+// tagAsyncSearchRequest tags the action and the ID of an async search
+// request. The spans are named the same whatever the action, see
+// legacySpanNames, so a polling loop reads as a sequence of identical spans.
+func tagAsyncSearchRequest(span zipkin.Span, m EndpointMatch) {
+	action, ok := asyncSearchAction(m)
+	if !ok {
+		return
+	}
+
+	span.Tag("es.async_search.action", action)
+	if m.ID != "" {
+		span.Tag("es.async_search.id", m.ID)
+	}
+}
+
+// tagAsyncSearchResponse tags the ID and the state of the async search
+// returned by a submit, get or status response.
+func tagAsyncSearchResponse(span zipkin.Span, body []byte) error {
+	res := struct {
+		ID        string `json:"id"`
+		IsPartial *bool  `json:"is_partial"`
+		IsRunning *bool  `json:"is_running"`
+	}{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	if res.ID != "" {
+		span.Tag("es.async_search.id", res.ID)
+	}
+	if res.IsPartial != nil {
+		span.Tag("es.async_search.is_partial", fmt.Sprintf("%t", *res.IsPartial))
+	}
+	if res.IsRunning != nil {
+		span.Tag("es.async_search.is_running", fmt.Sprintf("%t", *res.IsRunning))
+	}
+	return nil
+}
+
+// isAsyncSearchResponse tells whether the response of the endpoint describes
+// an async search, which is the case for all the actions but delete.
+func isAsyncSearchResponse(m EndpointMatch) bool {
+	action, ok := asyncSearchAction(m)
+	return ok && action != "delete"
+}
@@ -0,0 +1,84 @@
+package zipkines
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestAsyncSearchIsTagged(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := "FmRldE8zREVEUzA2ZVpUeGs2ejJFUFEaMkZ5QTVrSTZSaVN3WlNFVmtlWHJsdzoxMDc="
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case "POST":
+			fmt.Fprintf(rw, `{"id":%q,"is_partial":true,"is_running":true}`, id)
+		case "GET":
+			fmt.Fprintf(rw, `{"id":%q,"is_partial":false,"is_running":false,"response":{}}`, id)
+		default:
+			rw.Write([]byte(`{"acknowledged":true}`))
+		}
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+	for _, r := range []struct{ method, path string }{
+		{"POST", "/orders/_async_search?wait_for_completion_timeout=1s"},
+		{"GET", "/_async_search/" + id},
+		{"DELETE", "/_async_search/" + id},
+	} {
+		req, _ := http.NewRequest(r.method, srv.URL+r.path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 3, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expectedTags := []map[string]string{
+		{
+			"es.async_search.action":     "submit",
+			"es.async_search.id":         id,
+			"es.async_search.is_partial": "true",
+			"es.async_search.is_running": "true",
+		},
+		{
+			"es.async_search.action":     "get",
+			"es.async_search.id":         id,
+			"es.async_search.is_partial": "false",
+			"es.async_search.is_running": "false",
+		},
+		{
+			"es.async_search.action":     "delete",
+			"es.async_search.id":         id,
+			"es.async_search.is_running": "",
+		},
+	}
+	for i, span := range spans {
+		if want, have := "es/_async_search", span.Name; want != have {
+			t.Errorf("unexpected span name %d; want %q, have %q", i, want, have)
+		}
+
+		for key, val := range expectedTags[i] {
+			if want, have := val, span.Tags[key]; want != have {
+				t.Errorf("unexpected %q tag for span %d; want %q, have %q", key, i, want, have)
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package zipkines
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+)
+
+// defaultMaxChunkedRead is the default amount of bytes read from a response
+// body of unknown length, e.g. chunked, to extract tags from it.
+const defaultMaxChunkedRead = 4 << 20
+
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// replayBody returns a body replaying the already read bytes in front of the
+// rest of the original body, closing the original body on Close.
+func replayBody(read []byte, orig io.ReadCloser) io.ReadCloser {
+	return multiReadCloser{io.MultiReader(bytes.NewReader(read), orig), orig}
+}
+
+// rewrapRequestBody returns a shallow copy of the request whose body replays
+// the already read bytes, as the original request must not be modified.
+// The body length is known once it is read, hence the ContentLength and
+// GetBody are set accordingly.
+func rewrapRequestBody(req *http.Request, body []byte) *http.Request {
+	orig := req.Body
+	req = req.WithContext(req.Context())
+	req.Body = multiReadCloser{bytes.NewReader(body), orig}
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	return req
+}
+
+// readRequestBody reads the request body to extract tags from it and returns
+// a shallow copy of the request replaying it. Bodies are never read beyond
+// the configured limit: the ones known to exceed it are not read at all and
+// the streamed ones, i.e. of unknown length as sent by the bulk indexer of
+// the official client, are read up to it. A nil body is returned when the
+// body exceeds the limit, in which case the request replays the read bytes
+// in front of the rest of the stream.
+func (r *Transport) readRequestBody(req *http.Request) (*http.Request, []byte, error) {
+	limit := r.bodyReadLimit(-1)
+	if limit > 0 && req.ContentLength > limit {
+		r.skipBodyRead()
+		return req, nil, nil
+	}
+
+	// for client requests a zero length with a body means unknown
+	if req.ContentLength <= 0 && limit > 0 {
+		read, err := ioutil.ReadAll(io.LimitReader(req.Body, limit+1))
+		if err != nil {
+			return req, nil, err
+		}
+
+		if int64(len(read)) > limit {
+			r.skipBodyRead()
+			orig := req.Body
+			req = req.WithContext(req.Context())
+			req.Body = replayBody(read, orig)
+			return req, nil, nil
+		}
+		return rewrapRequestBody(req, read), read, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return req, nil, err
+	}
+	return rewrapRequestBody(req, body), body, nil
+}
+
+// readResponseBody reads the response body to extract tags from it and
+// re-wraps it so the caller can read it again, closing the original body on
+// Close. The ContentLength is left untouched. When the length of the body
+// is unknown no more than the configured limit is read. If the body exceeds
+// it, the read bytes are replayed in front of the rest of the body and false
+// is returned as the body is incomplete. The read bytes are also replayed when
+// reading fails, so the caller gets the response as the parent returned it
+// and hits the same failure when reading it.
+func (r *Transport) readResponseBody(res *http.Response) ([]byte, bool, error) {
+	limit := r.bodyReadLimit(res.ContentLength)
+	if limit > 0 && res.ContentLength > limit {
+		r.skipBodyRead()
+		return nil, false, nil
+	}
+
+	if res.ContentLength < 0 && limit > 0 {
+		body, err := ioutil.ReadAll(io.LimitReader(res.Body, limit+1))
+		res.Body = replayBody(body, res.Body)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if int64(len(body)) > limit {
+			r.skipBodyRead()
+			return nil, false, nil
+		}
+		return body, true, nil
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		res.Body = replayBody(body, res.Body)
+		return nil, false, err
+	}
+
+	res.Body = multiReadCloser{bytes.NewReader(body), res.Body}
+	return body, true, nil
+}
+
+// bodyReadLimit returns the maximum amount of bytes read from a body of the
+// given length, negative if unknown, to extract tags from it. Zero means no
+// limit.
+func (r *Transport) bodyReadLimit(length int64) int64 {
+	limit := r.maxBodyRead
+	if length < 0 && r.maxChunkedRead > 0 && (limit <= 0 || r.maxChunkedRead < limit) {
+		limit = r.maxChunkedRead
+	}
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+func (r *Transport) skipBodyRead() {
+	atomic.AddUint64(&r.skippedBodyReads, 1)
+}
+
+// SkippedBodyReads returns the number of bodies which were not tagged for
+// exceeding the read limits.
+func (r *Transport) SkippedBodyReads() uint64 {
+	return atomic.LoadUint64(&r.skippedBodyReads)
+}
+
+// WithMaxChunkedBodyRead sets the maximum amount of bytes read from response
+// bodies of unknown length (e.g. chunked) and from request bodies to extract
+// tags from them. Bodies exceeding it are passed through untouched and no
+// tags are extracted. It defaults to 4MB, a non positive value removes the
+// limit.
+func WithMaxChunkedBodyRead(n int64) TraceOpt {
+	return func(r *Transport) {
+		r.maxChunkedRead = n
+	}
+}
+
+// WithMaxBodyRead sets the maximum amount of bytes read from any body to
+// extract tags from it, including the responses of known length which are
+// otherwise read entirely, e.g. large scroll or aggregation responses. The
+// bodies exceeding it are passed through untouched and no tags are extracted
+// from them, they are counted by SkippedBodyReads. A non positive value, the
+// default, removes the limit. The lower of this and WithMaxChunkedBodyRead
+// applies to the bodies it covers.
+func WithMaxBodyRead(n int64) TraceOpt {
+	return func(r *Transport) {
+		r.maxBodyRead = n
+	}
+}
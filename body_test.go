@@ -0,0 +1,350 @@
+package zipkines
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestChunkedResponseOverLimitIsNotParsed(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	responseBody := `{"hits":{"total":274},"padding":"` + strings.Repeat("x", 64) + `"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(responseBody[:10]))
+		rw.(http.Flusher).Flush()
+		rw.Write([]byte(responseBody[10:]))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagTotalHits(), WithMaxChunkedBodyRead(32))
+	req, _ := http.NewRequest("GET", srv.URL+"/_search", nil)
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, have := int64(-1), res.ContentLength; want != have {
+		t.Fatalf("expected chunked response; want %d, have %d", want, have)
+	}
+
+	actualBody, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if want, have := responseBody, string(actualBody); want != have {
+		t.Errorf("unexpected response body; want %q, have %q", want, have)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if _, ok := spans[0].Tags["es.hits.total"]; ok {
+		t.Errorf("unexpected hits tag")
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+type staticRoundTripper struct {
+	res     *http.Response
+	reqBody []byte
+	req     *http.Request
+}
+
+func (rt *staticRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.req = req
+	rt.reqBody, _ = ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	return rt.res, nil
+}
+
+func TestBodiesAreRewrappedPreservingSemantics(t *testing.T) {
+	tracer, err := zipkin.NewTracer(recorder.NewReporter(), zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	requestBody := `{"size":25}`
+	responseBody := `{"_shards":{"total":6},"hits":{"total":274}}`
+	resBody := &closeRecorder{Reader: strings.NewReader(responseBody)}
+	parent := &staticRoundTripper{res: &http.Response{
+		StatusCode:    200,
+		Body:          resBody,
+		ContentLength: int64(len(responseBody)),
+	}}
+
+	transport := NewTransport(tracer, RoundTripper(parent), WithTagQuery(), WithTagTotalHits())
+
+	reqBody := &closeRecorder{Reader: strings.NewReader(requestBody)}
+	req, _ := http.NewRequest("POST", "http://localhost:9200/_search", reqBody)
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, have := requestBody, string(parent.reqBody); want != have {
+		t.Errorf("unexpected request body; want %q, have %q", want, have)
+	}
+
+	if want, have := int64(len(requestBody)), parent.req.ContentLength; want != have {
+		t.Errorf("unexpected request content length; want %d, have %d", want, have)
+	}
+
+	if !reqBody.closed {
+		t.Errorf("expected original request body to be closed")
+	}
+
+	if req.Body != reqBody {
+		t.Errorf("unexpected modification of the original request")
+	}
+
+	if parent.req.GetBody == nil {
+		t.Fatalf("expected GetBody to be set")
+	}
+	replayed, _ := parent.req.GetBody()
+	replayedBody, _ := ioutil.ReadAll(replayed)
+	if want, have := requestBody, string(replayedBody); want != have {
+		t.Errorf("unexpected replayed request body; want %q, have %q", want, have)
+	}
+
+	if want, have := int64(len(responseBody)), res.ContentLength; want != have {
+		t.Errorf("unexpected response content length; want %d, have %d", want, have)
+	}
+
+	actualBody, _ := ioutil.ReadAll(res.Body)
+	if want, have := responseBody, string(actualBody); want != have {
+		t.Errorf("unexpected response body; want %q, have %q", want, have)
+	}
+
+	if resBody.closed {
+		t.Errorf("unexpected close of the original response body before the caller closes it")
+	}
+
+	res.Body.Close()
+	if !resBody.closed {
+		t.Errorf("expected original response body to be closed")
+	}
+}
+
+func TestResponseIsKeptOnBodyReadFailure(t *testing.T) {
+	tracer, err := zipkin.NewTracer(recorder.NewReporter(), zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	readErr := errors.New("connection reset")
+	responseBody := `{"hits":`
+	for _, statusCode := range []int{200, 500} {
+		resBody := &closeRecorder{Reader: io.MultiReader(strings.NewReader(responseBody), &errorReader{readErr})}
+		parent := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: statusCode, Body: resBody, ContentLength: -1}, nil
+		})
+
+		transport := NewTransport(tracer, RoundTripper(parent), WithTagTotalHits(), WithTagErrorType(), WithLogger(discardLogger))
+
+		req, _ := http.NewRequest("GET", "http://localhost:9200/_search", nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		actualBody, err := ioutil.ReadAll(res.Body)
+		if want, have := readErr, err; want != have {
+			t.Errorf("unexpected read error for status %d; want %v, have %v", statusCode, want, have)
+		}
+
+		if want, have := responseBody, string(actualBody); want != have {
+			t.Errorf("unexpected response body for status %d; want %q, have %q", statusCode, want, have)
+		}
+
+		res.Body.Close()
+		if !resBody.closed {
+			t.Errorf("expected original response body to be closed for status %d", statusCode)
+		}
+	}
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func TestBodiesAreNotReadForUnsampledSpans(t *testing.T) {
+	tracer, err := zipkin.NewTracer(recorder.NewReporter(), zipkin.WithSampler(zipkin.NeverSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	responseBody := `{"hits":{"total":274}}`
+	resBody := ioutil.NopCloser(strings.NewReader(responseBody))
+	parent := &staticRoundTripper{res: &http.Response{StatusCode: 200, Body: resBody, ContentLength: -1}}
+
+	transport := NewTransport(tracer, RoundTripper(parent), WithTagQuery(), WithTagTotalHits())
+	req, _ := http.NewRequest("POST", "http://localhost:9200/_search", strings.NewReader(`{"size":25}`))
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if res.Body != resBody {
+		t.Errorf("expected the response body to be untouched")
+	}
+
+	if parent.req != req {
+		t.Errorf("expected the request to be untouched")
+	}
+}
+
+func TestStreamedRequestBodies(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		received = append(received, string(body))
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	// the bulk indexer of the official client streams its NDJSON payloads
+	line := "{\"index\":{}}\n{\"message\":\"hello\"}\n"
+	small := strings.Repeat(line, 2)
+	big := strings.Repeat(line, 10)
+
+	transport := NewTransport(tracer, WithTagQuery(), WithMaxChunkedBodyRead(int64(len(small))))
+	for _, payload := range []string{small, big} {
+		pr, pw := io.Pipe()
+		go func(payload string) {
+			for i := 0; i < len(payload); i += len(line) {
+				pw.Write([]byte(payload[i : i+len(line)]))
+			}
+			pw.Close()
+		}(payload)
+
+		req, _ := http.NewRequest("POST", srv.URL+"/_bulk", pr)
+		if want, have := int64(0), req.ContentLength; want != have {
+			t.Fatalf("unexpected content length; want %d, have %d", want, have)
+		}
+
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if want, have := 2, len(received); want != have {
+		t.Fatalf("unexpected requests number; want %d, have %d", want, have)
+	}
+
+	for i, payload := range []string{small, big} {
+		if want, have := payload, received[i]; want != have {
+			t.Errorf("unexpected body received; want %q, have %q", want, have)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "2", spans[0].Tags["es.bulk.docs"]; want != have {
+		t.Errorf("unexpected docs for the streamed body within the limit; want %q, have %q", want, have)
+	}
+
+	if want, have := "", spans[1].Tags["es.bulk.docs"]; want != have {
+		t.Errorf("unexpected docs for the streamed body over the limit; want %q, have %q", want, have)
+	}
+}
+
+func TestRequestBodyOverLimitIsNotRead(t *testing.T) {
+	tracer, _ := zipkin.NewTracer(recorder.NewReporter(), zipkin.WithSampler(zipkin.AlwaysSample))
+	transport := NewTransport(tracer, WithMaxChunkedBodyRead(4))
+
+	body := &closeRecorder{Reader: strings.NewReader(`{"query":{}}`)}
+	req, _ := http.NewRequest("POST", "http://localhost/_search", body)
+	req.ContentLength = 12
+
+	newReq, read, err := transport.readRequestBody(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if read != nil {
+		t.Errorf("unexpected body read: %q", read)
+	}
+
+	if newReq != req {
+		t.Error("unexpected request copy for a body not read")
+	}
+}
+
+func TestMaxBodyRead(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/big/_search" {
+			rw.Write([]byte(`{"hits":{"total":42,"hits":[{"_id":"1"},{"_id":"2"},{"_id":"3"}]}}`))
+			return
+		}
+		rw.Write([]byte(`{"hits":{"total":1}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagTotalHits(), WithMaxBodyRead(32))
+	for _, path := range []string{"/big/_search", "/small/_search"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		body, _ := ioutil.ReadAll(res.Body)
+		if want, have := res.ContentLength, int64(len(body)); want != have {
+			t.Errorf("unexpected body length; want %d, have %d", want, have)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "", spans[0].Tags["es.hits.total"]; want != have {
+		t.Errorf("unexpected hits total for the big response; want %q, have %q", want, have)
+	}
+
+	if want, have := "1", spans[1].Tags["es.hits.total"]; want != have {
+		t.Errorf("unexpected hits total for the small response; want %q, have %q", want, have)
+	}
+
+	if want, have := uint64(1), transport.SkippedBodyReads(); want != have {
+		t.Errorf("unexpected skipped body reads; want %d, have %d", want, have)
+	}
+}
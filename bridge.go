@@ -0,0 +1,174 @@
+package zipkines
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter"
+)
+
+// BridgeTracer is the minimal tracer API needed to emit the ES spans with
+// another tracing library, e.g. OpenTelemetry during a migration from
+// Zipkin. An adapter for an OpenTelemetry trace.Tracer looks like:
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, start time.Time) zipkines.BridgeSpan {
+//		_, span := t.tracer.Start(ctx, name, trace.WithTimestamp(start), trace.WithSpanKind(trace.SpanKindClient))
+//		return otelSpan{span}
+//	}
+//
+//	func (s otelSpan) SpanContext() ([16]byte, [8]byte, bool) {
+//		sc := s.Span.SpanContext()
+//		return [16]byte(sc.TraceID()), [8]byte(sc.SpanID()), sc.IsSampled()
+//	}
+type BridgeTracer interface {
+	// Start starts a client span, child of the span in the context if any.
+	Start(ctx context.Context, name string, start time.Time) BridgeSpan
+}
+
+// BridgeSpan is the minimal span API needed by the bridge.
+type BridgeSpan interface {
+	SetName(name string)
+	// SetAttribute records a tag, e.g. "es.hits.total".
+	SetAttribute(key, value string)
+	// AddEvent records an annotation.
+	AddEvent(name string, at time.Time)
+	// SetError records the "error" tag, e.g. as the span status.
+	SetError(description string)
+	// SetRemoteEndpoint records the ES node the request is sent to. The IP
+	// is nil when the node is addressed by its host name.
+	SetRemoteEndpoint(serviceName string, ip net.IP, port uint16)
+	// SpanContext returns the IDs of the span and whether it is sampled.
+	// They are the ones propagated to ES and logged, in place of the ones
+	// of the Zipkin span used underneath. A zero trace ID means the span
+	// has none.
+	SpanContext() (traceID [16]byte, spanID [8]byte, sampled bool)
+	End(at time.Time)
+}
+
+// bridgedSpan forwards every call to the actual span and to the bridged
+// span, whose context it takes over so the propagated IDs and the sampling
+// decision are the ones of the emitted span.
+type bridgedSpan struct {
+	zipkin.Span
+	bridged BridgeSpan
+	start   time.Time
+	sc      model.SpanContext
+
+	mu       sync.Mutex
+	errored  bool
+	finished bool
+}
+
+func (s *bridgedSpan) Context() model.SpanContext {
+	return s.sc
+}
+
+func (s *bridgedSpan) SetRemoteEndpoint(e *model.Endpoint) {
+	s.Span.SetRemoteEndpoint(e)
+	if e == nil {
+		return
+	}
+
+	ip := e.IPv4
+	if ip == nil {
+		ip = e.IPv6
+	}
+	s.bridged.SetRemoteEndpoint(e.ServiceName, ip, e.Port)
+}
+
+func (s *bridgedSpan) SetName(name string) {
+	s.Span.SetName(name)
+	s.bridged.SetName(name)
+}
+
+func (s *bridgedSpan) Annotate(t time.Time, value string) {
+	s.Span.Annotate(t, value)
+	s.bridged.AddEvent(value, t)
+}
+
+func (s *bridgedSpan) Tag(key, value string) {
+	s.Span.Tag(key, value)
+	if key != string(zipkin.TagError) {
+		s.bridged.SetAttribute(key, value)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// as in the actual span the first error value is persisted
+	if !s.errored {
+		s.errored = true
+		s.bridged.SetError(value)
+	}
+}
+
+func (s *bridgedSpan) Finish() {
+	s.Span.Finish()
+	s.end(time.Now())
+}
+
+func (s *bridgedSpan) FinishedWithDuration(d time.Duration) {
+	s.Span.FinishedWithDuration(d)
+	s.end(s.start.Add(d))
+}
+
+func (s *bridgedSpan) end(at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.finished {
+		s.finished = true
+		s.bridged.End(at)
+	}
+}
+
+// NewBridgeTransport returns a Transport emitting its client spans through
+// the given tracer instead of a Zipkin one, with the same names and tags as
+// they share the endpoint classification and the response parsing. The
+// Zipkin tracer used underneath samples everything, leaving the sampling to
+// the bridged tracer, and reports nothing. The trace headers, e.g. the
+// traceparent, carry the IDs of the bridged spans. The local spans, e.g. the
+// msearch items or the rollups, are not bridged.
+func NewBridgeTransport(tracer BridgeTracer, opts ...TraceOpt) (*Transport, error) {
+	zt, err := zipkin.NewTracer(reporter.NewNoopReporter(), zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		return nil, err
+	}
+
+	t := NewTransport(zt, opts...)
+	t.bridge = tracer
+	return t, nil
+}
+
+// bridgeSpan wraps the span of a request so it is emitted by the bridged
+// tracer as well.
+func (r *Transport) bridgeSpan(ctx context.Context, span zipkin.Span, name string) zipkin.Span {
+	if r.bridge == nil {
+		return span
+	}
+	start := time.Now()
+	bridged := r.bridge.Start(ctx, name, start)
+	return &bridgedSpan{Span: span, bridged: bridged, start: start, sc: bridgedContext(span, bridged)}
+}
+
+// bridgedContext returns the span context of the bridged span, or the one of
+// the Zipkin span if the bridged one has no trace ID.
+func bridgedContext(span zipkin.Span, bridged BridgeSpan) model.SpanContext {
+	traceID, spanID, sampled := bridged.SpanContext()
+	if traceID == ([16]byte{}) {
+		return span.Context()
+	}
+
+	return model.SpanContext{
+		TraceID: model.TraceID{
+			High: binary.BigEndian.Uint64(traceID[:8]),
+			Low:  binary.BigEndian.Uint64(traceID[8:]),
+		},
+		ID:      model.ID(binary.BigEndian.Uint64(spanID[:])),
+		Sampled: &sampled,
+	}
+}
@@ -0,0 +1,187 @@
+package zipkines
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type parentKey struct{}
+
+type fakeBridgeSpan struct {
+	mu     sync.Mutex
+	parent interface{}
+	name   string
+	attrs  map[string]string
+	events []string
+	err    string
+	ended  bool
+
+	traceID [16]byte
+	spanID  [8]byte
+	remote  string
+	ip      net.IP
+	port    uint16
+}
+
+func (s *fakeBridgeSpan) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
+
+func (s *fakeBridgeSpan) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs[key] = value
+}
+
+func (s *fakeBridgeSpan) AddEvent(name string, _ time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, name)
+}
+
+func (s *fakeBridgeSpan) SetError(description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = description
+}
+
+func (s *fakeBridgeSpan) SetRemoteEndpoint(serviceName string, ip net.IP, port uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remote, s.ip, s.port = serviceName, ip, port
+}
+
+func (s *fakeBridgeSpan) SpanContext() ([16]byte, [8]byte, bool) {
+	return s.traceID, s.spanID, true
+}
+
+func (s *fakeBridgeSpan) End(time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ended = true
+}
+
+type fakeBridgeTracer struct {
+	spans []*fakeBridgeSpan
+}
+
+func (t *fakeBridgeTracer) Start(ctx context.Context, name string, _ time.Time) BridgeSpan {
+	span := &fakeBridgeSpan{parent: ctx.Value(parentKey{}), name: name, attrs: map[string]string{}}
+	span.traceID[15] = byte(len(t.spans) + 1)
+	span.spanID[7] = byte(len(t.spans) + 1)
+	t.spans = append(t.spans, span)
+	return span
+}
+
+func TestBridgeTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/missing/_search" {
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"error":{"type":"index_not_found_exception","reason":"no such index [missing]"},"status":404}`))
+			return
+		}
+		rw.Write([]byte(`{"_shards":{"total":2,"successful":2},"hits":{"total":{"value":7,"relation":"eq"}}}`))
+	}))
+	defer srv.Close()
+
+	tracer := &fakeBridgeTracer{}
+	transport, err := NewBridgeTransport(tracer, WithTagTotalHits(), WithTagTotalShards(), WithTagErrorType())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), parentKey{}, "handler")
+	for _, path := range []string{"/orders/_search", "/missing/_search"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		res, err := transport.RoundTrip(req.WithContext(ctx))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	if want, have := 2, len(tracer.spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for _, span := range tracer.spans {
+		if want, have := "es/_search", span.name; want != have {
+			t.Errorf("unexpected span name; want %q, have %q", want, have)
+		}
+
+		if want, have := "handler", span.parent; want != have {
+			t.Errorf("unexpected parent; want %v, have %v", want, have)
+		}
+
+		if !span.ended {
+			t.Error("expected the span to be ended")
+		}
+	}
+
+	for key, val := range map[string]string{
+		"es.hits.total":    "7",
+		"es.shards.total":  "2",
+		"es.operation":     "search",
+		"http.status_code": "200",
+	} {
+		if want, have := val, tracer.spans[0].attrs[key]; want != have {
+			t.Errorf("unexpected %q attribute; want %q, have %q", key, want, have)
+		}
+	}
+
+	if want, have := "index_not_found_exception", tracer.spans[1].err; want != have {
+		t.Errorf("unexpected error; want %q, have %q", want, have)
+	}
+}
+
+func TestBridgeTransportPropagatesTheBridgedSpans(t *testing.T) {
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		traceparent = req.Header.Get("traceparent")
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tracer := &fakeBridgeTracer{}
+	transport, err := NewBridgeTransport(tracer, WithTraceContextPropagation(), WithRemoteServiceName("elasticsearch"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", srv.URL+"/orders/_search", nil)
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	if want, have := 1, len(tracer.spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "00-00000000000000000000000000000001-0000000000000001-01", traceparent; want != have {
+		t.Errorf("unexpected traceparent; want %q, have %q", want, have)
+	}
+
+	span := tracer.spans[0]
+	if want, have := "elasticsearch", span.remote; want != have {
+		t.Errorf("unexpected remote service name; want %q, have %q", want, have)
+	}
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	if want, have := port, fmt.Sprintf("%d", span.port); want != have {
+		t.Errorf("unexpected remote port; want %q, have %q", want, have)
+	}
+
+	if want, have := "127.0.0.1", span.ip.String(); want != have {
+		t.Errorf("unexpected remote IP; want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,268 @@
+package zipkines
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+)
+
+// traceEntry holds the ES spans accounting of a single trace.
+type traceEntry struct {
+	spans    int
+	lastSeen time.Time
+
+	nPlusOne int
+	queries  map[string]*queryBurst
+
+	overflow      zipkin.Span
+	overflowCount int
+	overflowStart time.Time
+	overflowEnd   time.Time
+}
+
+// queryBurst counts the queries sharing a fingerprint in a trace. The span
+// of the first one is held unfinished until both its request and the trace
+// are over, so it is tagged before being reported even when it is wrapped,
+// e.g. by the additional reporter or the bridge.
+type queryBurst struct {
+	first zipkin.Span
+	// count is guarded by the ledger lock.
+	count int
+
+	mu       sync.Mutex
+	duration time.Duration
+	done     bool
+	released bool
+}
+
+// requestDone records the duration of the held span once its request is
+// over, finishing it if the trace is already over.
+func (b *queryBurst) requestDone(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done = true
+	b.duration = d
+	if b.released {
+		b.first.FinishedWithDuration(b.duration)
+	}
+}
+
+// traceDone tags the held span with the count of the queries if they reach
+// the threshold and finishes it if its request is already over.
+func (b *queryBurst) traceDone(count, threshold int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.released = true
+	if count >= threshold {
+		b.first.Tag("es.n_plus_one.count", fmt.Sprintf("%d", count))
+	}
+	if b.done {
+		b.first.FinishedWithDuration(b.duration)
+	}
+}
+
+// finish reports the summarizing span of the overflow calls and the held
+// spans, if any.
+func (e *traceEntry) finish() {
+	if e.overflow != nil {
+		e.overflow.FinishedWithDuration(e.overflowEnd.Sub(e.overflowStart))
+	}
+
+	for _, b := range e.queries {
+		b.traceDone(b.count, e.nPlusOne)
+	}
+}
+
+// traceLedger keeps per trace accounting of the ES spans. Traces are
+// forgotten once they have been idle, i.e. without ES calls, for the idle
+// timeout as there is no way to tell when a trace is over.
+type traceLedger struct {
+	mu sync.Mutex
+	// limit is the span budget of a trace, zero means unlimited.
+	limit int
+	// nPlusOne is the number of identical queries in a trace considered an
+	// N+1 pattern, zero disables the detection.
+	nPlusOne  int
+	idle      time.Duration
+	lastSweep time.Time
+	traces    map[model.TraceID]*traceEntry
+}
+
+func newTraceLedger(idle time.Duration) *traceLedger {
+	return &traceLedger{
+		idle:   idle,
+		traces: map[model.TraceID]*traceEntry{},
+	}
+}
+
+// admit counts a call in the trace and tells whether it fits in the span
+// budget. The entries of the idle traces swept meanwhile are returned so the
+// caller can finish them outside of the lock.
+func (l *traceLedger) admit(traceID model.TraceID, now time.Time) (bool, []*traceEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expired := l.sweep(now)
+
+	e, ok := l.traces[traceID]
+	if !ok {
+		e = &traceEntry{}
+		l.traces[traceID] = e
+	}
+	e.lastSeen = now
+	e.spans++
+	return l.limit == 0 || e.spans <= l.limit, expired
+}
+
+func (l *traceLedger) sweep(now time.Time) []*traceEntry {
+	if now.Sub(l.lastSweep) < l.idle {
+		return nil
+	}
+	l.lastSweep = now
+
+	var expired []*traceEntry
+	for traceID, e := range l.traces {
+		if now.Sub(e.lastSeen) >= l.idle {
+			expired = append(expired, e)
+			delete(l.traces, traceID)
+		}
+	}
+	return expired
+}
+
+// recordOverflow accounts a call which did not fit in the budget of its
+// trace in the summarizing span, starting it with the first overflow call.
+func (l *traceLedger) recordOverflow(tracer *zipkin.Tracer, name string, parent model.SpanContext, start, end time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.traces[parent.TraceID]
+	if !ok {
+		// swept while the call was in flight, it is not worth a summary.
+		return
+	}
+
+	if e.overflow == nil {
+		e.overflow = tracer.StartSpan(name, zipkin.Parent(parent), zipkin.StartTime(start))
+		e.overflow.Tag("es.budget.limit", fmt.Sprintf("%d", l.limit))
+		e.overflowStart = start
+	}
+	e.overflowCount++
+	e.overflowEnd = end
+	e.overflow.Tag("es.budget.overflow.count", fmt.Sprintf("%d", e.overflowCount))
+}
+
+// recordQuery counts a query in its trace and returns its burst if its span
+// is the first one with the fingerprint, in which case the span is held by
+// the ledger and must be handed back through requestDone instead of being
+// finished by the caller.
+func (l *traceLedger) recordQuery(traceID model.TraceID, fingerprint string, span zipkin.Span) *queryBurst {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.traces[traceID]
+	if !ok {
+		return nil
+	}
+
+	if e.queries == nil {
+		e.nPlusOne = l.nPlusOne
+		e.queries = map[string]*queryBurst{}
+	}
+
+	if b, ok := e.queries[fingerprint]; ok {
+		b.count++
+		return nil
+	}
+	b := &queryBurst{first: span, count: 1}
+	e.queries[fingerprint] = b
+	return b
+}
+
+// flush forgets all the traces and returns their entries.
+func (l *traceLedger) flush() []*traceEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := make([]*traceEntry, 0, len(l.traces))
+	for traceID, e := range l.traces {
+		entries = append(entries, e)
+		delete(l.traces, traceID)
+	}
+	return entries
+}
+
+func finishEntries(entries []*traceEntry) {
+	for _, e := range entries {
+		e.finish()
+	}
+}
+
+// admitSpan tells whether a call with the given parent gets its own span
+// according to the span budget of its trace.
+func (r *Transport) admitSpan(parent model.SpanContext) bool {
+	if r.ledger == nil {
+		return true
+	}
+
+	admitted, expired := r.ledger.admit(parent.TraceID, r.now())
+	finishEntries(expired)
+	return admitted
+}
+
+// overflowRoundTrip passes a call exceeding the span budget through to the
+// parent transport, accounting it in the summarizing span.
+func (r *Transport) overflowRoundTrip(req *http.Request, parent model.SpanContext) (*http.Response, error) {
+	start := time.Now()
+	res, err := r.parent.RoundTrip(req)
+	r.ledger.recordOverflow(r.tracer, r.spanName("es/budget.overflow"), parent, start, time.Now())
+	return res, err
+}
+
+// Flush reports the pending summarizing spans of the span budget and the
+// spans held by the N+1 detection, e.g. before shutting down. The trace
+// accounting starts over afterwards.
+func (r *Transport) Flush() {
+	if r.ledger != nil {
+		finishEntries(r.ledger.flush())
+	}
+}
+
+// WithSpanBudget limits the number of ES spans a single trace can contain
+// to protect the tracing backend from N+1 query storms. The calls exceeding
+// the limit get no span of their own but are summarized in a single
+// "es/budget.overflow" span per trace tagged with their count as
+// "es.budget.overflow.count". The summary is reported once the trace made no
+// ES calls for the idle timeout, which is checked on the following calls, or
+// on Flush. Calls starting a new trace are never limited.
+func WithSpanBudget(limit int, idle time.Duration) TraceOpt {
+	return func(r *Transport) {
+		r.ensureLedger(idle).limit = limit
+	}
+}
+
+// WithNPlusOneDetection detects the N+1 query pattern, i.e. the same query
+// with different values sent over and over within a trace, e.g. one per
+// item of a list. The first span of each query fingerprint in a trace is held
+// until the trace made no ES calls for the idle timeout, or until Flush, and
+// it gets tagged with "es.n_plus_one.count" when the trace sent the query at
+// least threshold times.
+func WithNPlusOneDetection(threshold int, idle time.Duration) TraceOpt {
+	return func(r *Transport) {
+		r.ensureLedger(idle).nPlusOne = threshold
+	}
+}
+
+// ensureLedger returns the trace ledger shared by the per trace features,
+// the last idle timeout given wins.
+func (r *Transport) ensureLedger(idle time.Duration) *traceLedger {
+	if r.ledger == nil {
+		r.ledger = newTraceLedger(idle)
+	}
+	r.ledger.idle = idle
+	return r.ledger
+}
@@ -0,0 +1,283 @@
+package zipkines
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestSpanBudget(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithSpanBudget(2, time.Minute))
+
+	parent := tracer.StartSpan("parent")
+	ctx := zipkin.NewContext(context.Background(), parent)
+	for i := 0; i < 5; i++ {
+		req, _ := http.NewRequest("GET", srv.URL+"/logs/_doc/1", nil)
+		if _, err := transport.RoundTrip(req.WithContext(ctx)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if want, have := 5, calls; want != have {
+		t.Errorf("unexpected calls number; want %d, have %d", want, have)
+	}
+
+	if want, have := 2, len(reporter.Flush()); want != have {
+		t.Fatalf("unexpected spans number before flushing; want %d, have %d", want, have)
+	}
+
+	transport.Flush()
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "es/budget.overflow", spans[0].Name; want != have {
+		t.Errorf("unexpected span name; want %q, have %q", want, have)
+	}
+
+	if want, have := "3", spans[0].Tags["es.budget.overflow.count"]; want != have {
+		t.Errorf("unexpected overflow count; want %q, have %q", want, have)
+	}
+
+	if want, have := parent.Context().ID, *spans[0].ParentID; want != have {
+		t.Errorf("unexpected parent ID; want %s, have %s", want, have)
+	}
+}
+
+func TestSpanBudgetIdleTraceIsReported(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	transport := NewTransport(tracer, WithSpanBudget(1, time.Minute), WithClock(clock))
+
+	first := zipkin.NewContext(context.Background(), tracer.StartSpan("first"))
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", srv.URL+"/logs/_doc/1", nil)
+		transport.RoundTrip(req.WithContext(first))
+	}
+	reporter.Flush()
+
+	now = now.Add(2 * time.Minute)
+	second := zipkin.NewContext(context.Background(), tracer.StartSpan("second"))
+	req, _ := http.NewRequest("GET", srv.URL+"/logs/_doc/1", nil)
+	transport.RoundTrip(req.WithContext(second))
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "es/budget.overflow", spans[0].Name; want != have {
+		t.Errorf("unexpected span name; want %q, have %q", want, have)
+	}
+
+	if want, have := "1", spans[0].Tags["es.budget.overflow.count"]; want != have {
+		t.Errorf("unexpected overflow count; want %q, have %q", want, have)
+	}
+}
+
+func TestNPlusOneDetection(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithNPlusOneDetection(3, time.Minute))
+
+	ctx := zipkin.NewContext(context.Background(), tracer.StartSpan("parent"))
+	queries := []string{
+		`{"query":{"term":{"user":"alice"}}}`,
+		`{"query":{"term":{"user":"bob"}}}`,
+		`{"query":{"match_all":{}}}`,
+		`{"query":{"term":{"user":"carol"}}}`,
+	}
+	for _, query := range queries {
+		req, _ := http.NewRequest("POST", srv.URL+"/users/_search", strings.NewReader(query))
+		if _, err := transport.RoundTrip(req.WithContext(ctx)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if want, have := 2, len(reporter.Flush()); want != have {
+		t.Fatalf("unexpected spans number before flushing; want %d, have %d", want, have)
+	}
+
+	transport.Flush()
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	var counts []string
+	for _, span := range spans {
+		if count, ok := span.Tags["es.n_plus_one.count"]; ok {
+			counts = append(counts, count)
+		}
+	}
+
+	if want, have := 1, len(counts); want != have {
+		t.Fatalf("unexpected tagged spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "3", counts[0]; want != have {
+		t.Errorf("unexpected N+1 count; want %q, have %q", want, have)
+	}
+}
+
+func TestNPlusOneHeldSpanIsReportedOnceFinished(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	received, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/slow/_search" {
+			close(received)
+			<-release
+		}
+	}))
+	defer srv.Close()
+
+	var (
+		mu  sync.Mutex
+		now = time.Unix(0, 0)
+	)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	transport := NewTransport(tracer, WithNPlusOneDetection(2, time.Minute), WithClock(clock))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctx := zipkin.NewContext(context.Background(), tracer.StartSpan("slow"))
+		req, _ := http.NewRequest("POST", srv.URL+"/slow/_search", strings.NewReader(`{"query":{"match_all":{}}}`))
+		if _, err := transport.RoundTrip(req.WithContext(ctx)); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}()
+
+	// the span of the slow call is held once its request is sent
+	<-received
+
+	// sweeps the trace of the slow call while it is in flight
+	mu.Lock()
+	now = now.Add(2 * time.Minute)
+	mu.Unlock()
+	ctx := zipkin.NewContext(context.Background(), tracer.StartSpan("fast"))
+	req, _ := http.NewRequest("POST", srv.URL+"/fast/_search", strings.NewReader(`{"query":{"match_all":{}}}`))
+	if _, err := transport.RoundTrip(req.WithContext(ctx)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, have := 0, len(reporter.Flush()); want != have {
+		t.Fatalf("unexpected spans number while in flight; want %d, have %d", want, have)
+	}
+
+	close(release)
+	<-done
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "/slow/_search", spans[0].Tags["http.path"]; want != have {
+		t.Errorf("unexpected path; want %q, have %q", want, have)
+	}
+
+	if spans[0].Duration == 0 {
+		t.Error("expected the held span to be finished")
+	}
+}
+
+func TestNPlusOneCountIsTaggedOnWrappedSpans(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	parentTracer, _ := zipkin.NewTracer(recorder.NewReporter(), zipkin.WithSampler(zipkin.AlwaysSample))
+	ctx := zipkin.NewContext(context.Background(), parentTracer.StartSpan("parent"))
+	send := func(transport *Transport) {
+		for _, user := range []string{"alice", "bob"} {
+			req, _ := http.NewRequest("POST", srv.URL+"/users/_search", strings.NewReader(`{"query":{"term":{"user":"`+user+`"}}}`))
+			if _, err := transport.RoundTrip(req.WithContext(ctx)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		transport.Flush()
+	}
+
+	additional := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(recorder.NewReporter(), zipkin.WithSampler(zipkin.AlwaysSample))
+	send(NewTransport(tracer, WithAdditionalReporter(additional), WithNPlusOneDetection(2, time.Minute)))
+
+	var counts []string
+	for _, span := range additional.Flush() {
+		if count, ok := span.Tags["es.n_plus_one.count"]; ok {
+			counts = append(counts, count)
+		}
+	}
+	if want, have := "[2]", fmt.Sprint(counts); want != have {
+		t.Errorf("unexpected mirrored N+1 counts; want %s, have %s", want, have)
+	}
+
+	bridge := &fakeBridgeTracer{}
+	transport, err := NewBridgeTransport(bridge, WithNPlusOneDetection(2, time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	send(transport)
+
+	if want, have := 2, len(bridge.spans); want != have {
+		t.Fatalf("unexpected bridged spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "2", bridge.spans[0].attrs["es.n_plus_one.count"]; want != have {
+		t.Errorf("unexpected bridged N+1 count; want %q, have %q", want, have)
+	}
+
+	for _, span := range bridge.spans {
+		if !span.ended {
+			t.Error("expected the bridged span to be ended")
+		}
+	}
+}
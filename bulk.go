@@ -0,0 +1,261 @@
+package zipkines
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// defaultBulkChunkSize is the default maximum size of the chunks sent by
+// SplitBulk, well under the 100MB default `http.max_content_length` of ES.
+const defaultBulkChunkSize = 10 << 20
+
+// readBulkItem reads a bulk item from a NDJSON payload, that is the action
+// line plus the source line for all actions but delete.
+func readBulkItem(r *bufio.Reader) ([]byte, error) {
+	action, err := readBulkLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := map[string]json.RawMessage{}
+	if err := json.Unmarshal(action, &meta); err != nil {
+		return nil, fmt.Errorf("invalid bulk action line: %v", err)
+	}
+
+	if _, ok := meta["delete"]; ok {
+		return action, nil
+	}
+
+	source, err := readBulkLine(r)
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return append(action, source...), nil
+}
+
+// readBulkLine reads the next non empty line including its line feed.
+func readBulkLine(r *bufio.Reader) ([]byte, error) {
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+			return line, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// SplitBulk sends a NDJSON bulk payload to the given `_bulk` URL through the
+// transport in chunks of at most maxChunkSize bytes, keeping every action
+// together with its source. An item larger than maxChunkSize is sent alone.
+// Every chunk gets its own span, child of the span in the context, tagged
+// with its sequence number, size and items count. The chunk responses are
+// passed to handle, if not nil, before being closed. It stops at the first
+// failed chunk. A non positive maxChunkSize defaults to 10MB.
+func (r *Transport) SplitBulk(
+	ctx context.Context,
+	url string,
+	payload io.Reader,
+	maxChunkSize int,
+	handle func(*http.Response) error,
+) error {
+	if maxChunkSize <= 0 {
+		maxChunkSize = defaultBulkChunkSize
+	}
+
+	br := bufio.NewReader(payload)
+	chunk := &bytes.Buffer{}
+	items, seq := 0, 0
+
+	flush := func() error {
+		if items == 0 {
+			return nil
+		}
+		seq++
+		err := r.sendBulkChunk(ctx, url, chunk.Bytes(), seq, items, handle)
+		chunk = &bytes.Buffer{}
+		items = 0
+		return err
+	}
+
+	for {
+		item, err := readBulkItem(br)
+		if err == io.EOF {
+			return flush()
+		}
+		if err != nil {
+			return err
+		}
+
+		if items > 0 && chunk.Len()+len(item) > maxChunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		chunk.Write(item)
+		items++
+	}
+}
+
+func (r *Transport) sendBulkChunk(
+	ctx context.Context,
+	url string,
+	chunk []byte,
+	seq, items int,
+	handle func(*http.Response) error,
+) error {
+	span, ctx := r.tracer.StartSpanFromContext(ctx, r.spanName("es/bulk.chunk"))
+	defer span.Finish()
+
+	span.Tag("es.bulk.chunk.seq", fmt.Sprintf("%d", seq))
+	span.Tag("es.bulk.chunk.items", fmt.Sprintf("%d", items))
+	span.Tag("es.bulk.chunk.bytes", fmt.Sprintf("%d", len(chunk)))
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(chunk))
+	if err != nil {
+		zipkin.TagError.Set(span, err.Error())
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	res, err := r.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		zipkin.TagError.Set(span, err.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	if handle != nil {
+		if err := handle(res); err != nil {
+			zipkin.TagError.Set(span, err.Error())
+			return err
+		}
+	}
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		zipkin.TagError.Set(span, fmt.Sprintf("%d", res.StatusCode))
+		return fmt.Errorf("bulk chunk %d failed with status code %d", seq, res.StatusCode)
+	}
+
+	return nil
+}
+
+// tagBulkActions tags the number of actions of every type, e.g.
+// "es.bulk.actions.index", and the total number of documents of a NDJSON bulk
+// payload.
+func tagBulkActions(span zipkin.Span, body []byte) error {
+	counts := map[string]int{}
+	docs := 0
+	r := bufio.NewReader(bytes.NewReader(body))
+	for {
+		item, err := readBulkItem(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		meta := map[string]json.RawMessage{}
+		// the action line was already validated by readBulkItem
+		json.NewDecoder(bytes.NewReader(item)).Decode(&meta)
+		for action := range meta {
+			counts[action]++
+		}
+		docs++
+	}
+
+	for action, count := range counts {
+		span.Tag("es.bulk.actions."+action, fmt.Sprintf("%d", count))
+	}
+	span.Tag("es.bulk.docs", fmt.Sprintf("%d", docs))
+	return nil
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Error *struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// maxBulkErrorTypes is the maximum number of distinct error types tagged for
+// a bulk response.
+const maxBulkErrorTypes = 3
+
+// tagBulkErrors tags the failures of the items of a bulk response, which is
+// successful as a whole even if items failed: the span is tagged as an error
+// along with the number of failed items and the first distinct error types.
+// If dominant is set the most frequent error type is tagged as well, along
+// with the number of items failing with it. Ties are broken alphabetically.
+func tagBulkErrors(span zipkin.Span, body []byte, dominant bool) error {
+	res := bulkResponse{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	if !res.Errors {
+		return nil
+	}
+
+	var failed int
+	var types []string
+	counts := map[string]int{}
+	for _, item := range res.Items {
+		for _, result := range item {
+			if result.Error == nil {
+				continue
+			}
+			failed++
+			if _, seen := counts[result.Error.Type]; !seen && len(types) < maxBulkErrorTypes {
+				types = append(types, result.Error.Type)
+			}
+			counts[result.Error.Type]++
+		}
+	}
+
+	var top string
+	var count int
+	for errType, n := range counts {
+		if n > count || (n == count && errType < top) {
+			top, count = errType, n
+		}
+	}
+
+	span.Tag("es.bulk.failed", fmt.Sprintf("%d", failed))
+	if count == 0 {
+		// errors is set but no item says why
+		zipkin.TagError.Set(span, "bulk item failures")
+		return nil
+	}
+
+	zipkin.TagError.Set(span, top)
+	span.Tag("es.bulk.error.types", strings.Join(types, ","))
+	if dominant {
+		span.Tag("es.bulk.error.type", top)
+		span.Tag("es.bulk.error.count", fmt.Sprintf("%d", count))
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package zipkines
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestSplitBulk(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var chunks []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		chunks = append(chunks, string(body))
+		rw.Write([]byte(`{"errors":false}`))
+	}))
+	defer srv.Close()
+
+	payload := `{"index":{"_index":"logs"}}
+{"message":"a"}
+{"delete":{"_index":"logs","_id":"1"}}
+
+{"update":{"_index":"logs","_id":"2"}}
+{"doc":{"message":"b"}}
+`
+
+	transport := NewTransport(tracer)
+	if err := transport.SplitBulk(context.Background(), srv.URL+"/_bulk", strings.NewReader(payload), 90, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedChunks := []string{
+		"{\"index\":{\"_index\":\"logs\"}}\n{\"message\":\"a\"}\n{\"delete\":{\"_index\":\"logs\",\"_id\":\"1\"}}\n",
+		"{\"update\":{\"_index\":\"logs\",\"_id\":\"2\"}}\n{\"doc\":{\"message\":\"b\"}}\n",
+	}
+	if want, have := len(expectedChunks), len(chunks); want != have {
+		t.Fatalf("unexpected chunks number; want %d, have %d", want, have)
+	}
+	for i := range expectedChunks {
+		if want, have := expectedChunks[i], chunks[i]; want != have {
+			t.Errorf("unexpected chunk %d; want %q, have %q", i, want, have)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 4, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	items := map[string]string{}
+	for _, span := range spans {
+		if span.Name == "es/bulk.chunk" {
+			items[span.Tags["es.bulk.chunk.seq"]] = span.Tags["es.bulk.chunk.items"]
+		}
+	}
+
+	if want, have := "2", items["1"]; want != have {
+		t.Errorf("unexpected items in first chunk; want %q, have %q", want, have)
+	}
+
+	if want, have := "1", items["2"]; want != have {
+		t.Errorf("unexpected items in second chunk; want %q, have %q", want, have)
+	}
+}
+
+func TestBulkErrorsAreTagged(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"took":3,"errors":true,"items":[
+			{"index":{"status":201}},
+			{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}},
+			{"create":{"status":409,"error":{"type":"version_conflict_engine_exception"}}},
+			{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}
+		]}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagErrorType())
+	req, _ := http.NewRequest("POST", srv.URL+"/logs/_bulk", strings.NewReader("{}\n"))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expectedTags := map[string]string{
+		"error":               "mapper_parsing_exception",
+		"es.bulk.error.type":  "mapper_parsing_exception",
+		"es.bulk.error.count": "2",
+		"es.bulk.failed":      "3",
+		"es.bulk.error.types": "mapper_parsing_exception,version_conflict_engine_exception",
+	}
+	for key, val := range expectedTags {
+		if want, have := val, spans[0].Tags[key]; want != have {
+			t.Errorf("unexpected %q tag; want %q, have %q", key, want, have)
+		}
+	}
+}
+
+func TestSuccessfulBulkIsNotTaggedAsError(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"took":3,"errors":false,"items":[{"index":{"status":201}}]}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagErrorType())
+	req, _ := http.NewRequest("POST", srv.URL+"/_bulk", strings.NewReader("{}\n"))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := "", spans[0].Tags["error"]; want != have {
+		t.Errorf("unexpected error; want %q, have %q", want, have)
+	}
+}
+
+func TestBulkActionsAreTagged(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"errors":false}`))
+	}))
+	defer srv.Close()
+
+	payload := `{"index":{"_index":"logs"}}
+{"message":"a"}
+{"create":{"_index":"logs","_id":"1"}}
+{"message":"b"}
+{"delete":{"_index":"logs","_id":"2"}}
+{"update":{"_index":"logs","_id":"3"}}
+{"doc":{"message":"c"}}
+{"index":{"_index":"logs"}}
+{"message":"d"}
+`
+
+	transport := NewTransport(tracer, WithTagQuery())
+	req, _ := http.NewRequest("POST", srv.URL+"/logs/_bulk", strings.NewReader(payload))
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for tag, value := range map[string]string{
+		"es.bulk.actions.index":  "2",
+		"es.bulk.actions.create": "1",
+		"es.bulk.actions.delete": "1",
+		"es.bulk.actions.update": "1",
+		"es.bulk.docs":           "5",
+	} {
+		if want, have := value, spans[0].Tags[tag]; want != have {
+			t.Errorf("unexpected %s; want %q, have %q", tag, want, have)
+		}
+	}
+
+	if _, ok := spans[0].Tags["es.query"]; ok {
+		t.Error("expected the bulk payload not to be tagged")
+	}
+}
+
+func TestBulkFailuresAreTaggedByDefault(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"took":3,"errors":true,"items":[
+			{"index":{"status":201}},
+			{"update":{"status":404,"error":{"type":"document_missing_exception"}}}
+		]}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+	req, _ := http.NewRequest("POST", srv.URL+"/_bulk", strings.NewReader("{}\n"))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	expectedTags := map[string]string{
+		"error":               "document_missing_exception",
+		"es.bulk.failed":      "1",
+		"es.bulk.error.types": "document_missing_exception",
+		"es.bulk.error.type":  "",
+	}
+	for key, val := range expectedTags {
+		if want, have := val, spans[0].Tags[key]; want != have {
+			t.Errorf("unexpected %q tag; want %q, have %q", key, want, have)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package zipkines
+
+import (
+	"encoding/json"
+	"fmt"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+type byQueryResponse struct {
+	Total            *int `json:"total"`
+	Updated          *int `json:"updated"`
+	Deleted          *int `json:"deleted"`
+	VersionConflicts *int `json:"version_conflicts"`
+	Batches          *int `json:"batches"`
+	Failures         []struct {
+		// bulk failures carry a cause while search failures a reason
+		Cause *struct {
+			Type string `json:"type"`
+		} `json:"cause"`
+		Reason *struct {
+			Type string `json:"type"`
+		} `json:"reason"`
+	} `json:"failures"`
+}
+
+// isByQuery tells whether the endpoint is the update or delete by query API.
+func isByQuery(m EndpointMatch) bool {
+	return m.Operation == "update_by_query" || m.Operation == "delete_by_query"
+}
+
+// tagByQuery tags the counters of an update or delete by query response and
+// tags it as an error if any document failed.
+func tagByQuery(span zipkin.Span, body []byte) error {
+	res := byQueryResponse{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	for key, val := range map[string]*int{
+		"total":             res.Total,
+		"updated":           res.Updated,
+		"deleted":           res.Deleted,
+		"version_conflicts": res.VersionConflicts,
+		"batches":           res.Batches,
+	} {
+		if val != nil {
+			span.Tag("es.by_query."+key, fmt.Sprintf("%d", *val))
+		}
+	}
+
+	if len(res.Failures) == 0 {
+		return nil
+	}
+
+	span.Tag("es.by_query.failures", fmt.Sprintf("%d", len(res.Failures)))
+	errType := "by_query failures"
+	if f := res.Failures[0]; f.Cause != nil && f.Cause.Type != "" {
+		errType = f.Cause.Type
+	} else if f.Reason != nil && f.Reason.Type != "" {
+		errType = f.Reason.Type
+	}
+	zipkin.TagError.Set(span, errType)
+	return nil
+}
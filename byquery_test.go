@@ -0,0 +1,62 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestByQueryResponses(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/logs/_update_by_query" {
+			rw.Write([]byte(`{"took":147,"timed_out":false,"total":120,"updated":118,"deleted":0,"batches":2,
+				"version_conflicts":1,"failures":[{"index":"logs","id":"7","cause":{"type":"mapper_parsing_exception"},"status":400}]}`))
+			return
+		}
+		rw.Write([]byte(`{"took":20,"timed_out":false,"total":3,"deleted":3,"batches":1,"version_conflicts":0,"failures":[]}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+	for _, path := range []string{"/logs/_update_by_query", "/logs/_delete_by_query"} {
+		req, _ := http.NewRequest("POST", srv.URL+path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expectedTags := []map[string]string{
+		{
+			"es.by_query.total":             "120",
+			"es.by_query.updated":           "118",
+			"es.by_query.version_conflicts": "1",
+			"es.by_query.batches":           "2",
+			"es.by_query.failures":          "1",
+			"error":                         "mapper_parsing_exception",
+		},
+		{
+			"es.by_query.deleted":  "3",
+			"es.by_query.updated":  "",
+			"es.by_query.failures": "",
+			"error":                "",
+		},
+	}
+	for i, tags := range expectedTags {
+		for key, val := range tags {
+			if want, have := val, spans[i].Tags[key]; want != have {
+				t.Errorf("unexpected %q tag for span %d; want %q, have %q", key, i, want, have)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package zipkines
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// isCat tells whether the endpoint is one of the `_cat` APIs, whose
+// responses are plain text tables unless another format is requested.
+func isCat(m EndpointMatch) bool {
+	return strings.HasPrefix(m.Operation, "cat.")
+}
+
+// tagCatRows tags the number of rows of a `_cat` response as "es.cat.rows",
+// either a JSON array or a text table whose header, if requested with the
+// `v` param, is not counted. The other formats are not parsed.
+func tagCatRows(span zipkin.Span, req *http.Request, res *http.Response, body []byte) error {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var rows []json.RawMessage
+		if err := json.Unmarshal(trimmed, &rows); err != nil {
+			return err
+		}
+		span.Tag("es.cat.rows", fmt.Sprintf("%d", len(rows)))
+		return nil
+	}
+
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "text/plain") {
+		// e.g. yaml, cbor or smile.
+		return nil
+	}
+
+	var rows int
+	for _, line := range bytes.Split(trimmed, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			rows++
+		}
+	}
+	params := req.URL.Query()
+	if _, verbose := params["v"]; verbose && params.Get("v") != "false" && rows > 0 {
+		rows--
+	}
+	span.Tag("es.cat.rows", fmt.Sprintf("%d", rows))
+	return nil
+}
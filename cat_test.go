@@ -0,0 +1,91 @@
+package zipkines
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestCatResponses(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("format") == "json" {
+			rw.Header().Set("Content-Type", "application/json")
+			rw.Write([]byte(`[{"index":"orders"},{"index":"logs"}]`))
+			return
+		}
+		rw.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		rw.Write([]byte("health status index\ngreen  open   orders\nyellow open   logs\ngreen  open   users\n"))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagTotalHits(), WithTagTotalShards(), WithTagTook())
+	for _, path := range []string{"/_cat/indices?v", "/_cat/indices", "/_cat/indices?format=json"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", path, err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 3, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for i, rows := range []string{"3", "4", "2"} {
+		if want, have := rows, spans[i].Tags["es.cat.rows"]; want != have {
+			t.Errorf("unexpected rows for span %d; want %q, have %q", i, want, have)
+		}
+
+		if want, have := "", spans[i].Tags["error"]; want != have {
+			t.Errorf("unexpected error for span %d; want %q, have %q", i, want, have)
+		}
+	}
+}
+
+func TestTextResponsesAreNotParsed(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	const hotThreads = "::: {node-1}{abc}{127.0.0.1}\n   Hot threads at 2020-01-01T00:00:00Z\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		rw.Write([]byte(hotThreads))
+	}))
+	defer srv.Close()
+
+	out := &bytes.Buffer{}
+	transport := NewTransport(tracer, WithLogger(log.New(out, "", 0)), WithTagTotalHits(), WithTagTotalShards(), WithTagTook())
+	for _, path := range []string{"/_nodes/hot_threads", "/_sql?format=txt"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", path, err)
+		}
+
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if want, have := hotThreads, string(body); want != have {
+			t.Errorf("unexpected body for %s; want %q, have %q", path, want, have)
+		}
+	}
+
+	if want, have := 2, len(reporter.Flush()); want != have {
+		t.Errorf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if out.Len() > 0 {
+		t.Errorf("unexpected log: %s", out.String())
+	}
+}
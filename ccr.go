@@ -0,0 +1,77 @@
+package zipkines
+
+import (
+	"encoding/json"
+	"strings"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// ccrStatsRule tags the headline numbers of the cross cluster replication
+// stats responses, both for `/_ccr/stats` and `/{index}/_ccr/stats`.
+var ccrStatsRule = ResponsePointerRule{
+	Endpoint: "_ccr",
+	Tags: map[string]string{
+		"es.ccr.operations_written":          "/indices/*/shards/*/operations_written",
+		"es.ccr.failed_read_requests":        "/indices/*/shards/*/failed_read_requests",
+		"es.ccr.follow.operations_written":   "/follow_stats/indices/*/shards/*/operations_written",
+		"es.ccr.follow.failed_read_requests": "/follow_stats/indices/*/shards/*/failed_read_requests",
+		"es.ccr.auto_follow.failed_indices":  "/auto_follow_stats/number_of_failed_follow_indices",
+	},
+}
+
+// ccrCall describes a call to the cross cluster replication APIs.
+type ccrCall struct {
+	operation string
+	// index is the index in the path, which is the follower index for all
+	// operations but forget_follower where it is the leader one.
+	index string
+}
+
+// ccrCallOf returns the cross cluster replication call of an endpoint, e.g.
+// `PUT /{follower}/_ccr/follow`, or false if it is not one.
+func ccrCallOf(m EndpointMatch) (ccrCall, bool) {
+	if !strings.HasPrefix(m.Operation, "ccr.") {
+		return ccrCall{}, false
+	}
+	return ccrCall{operation: strings.TrimPrefix(m.Operation, "ccr."), index: m.Index}, true
+}
+
+func (c ccrCall) tag(span zipkin.Span) {
+	if c.index == "" {
+		return
+	}
+
+	if c.operation == "forget_follower" {
+		span.Tag("es.ccr.leader_index", c.index)
+	} else {
+		span.Tag("es.ccr.follower_index", c.index)
+	}
+}
+
+// readsBody tells whether the request body holds the leader index.
+func (c ccrCall) readsBody() bool {
+	return c.operation == "follow"
+}
+
+func (c ccrCall) isStats() bool {
+	return c.operation == "stats" || c.operation == "follow_stats"
+}
+
+// tagCCRFollowBody tags the leader index and cluster from a follow request.
+func tagCCRFollowBody(span zipkin.Span, body []byte) {
+	req := struct {
+		RemoteCluster string `json:"remote_cluster"`
+		LeaderIndex   string `json:"leader_index"`
+	}{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return
+	}
+
+	if req.LeaderIndex != "" {
+		span.Tag("es.ccr.leader_index", safeTagValue(req.LeaderIndex, 0))
+	}
+	if req.RemoteCluster != "" {
+		span.Tag("es.ccr.remote_cluster", safeTagValue(req.RemoteCluster, 0))
+	}
+}
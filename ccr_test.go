@@ -0,0 +1,83 @@
+package zipkines
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestCCRFollow(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	requestBody := `{"remote_cluster":"leader","leader_index":"logs"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"follow_index_created":true}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+	req, _ := http.NewRequest("PUT", srv.URL+"/logs-copy/_ccr/follow", bytes.NewBufferString(requestBody))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expectedTags := map[string]string{
+		"es.ccr.follower_index": "logs-copy",
+		"es.ccr.leader_index":   "logs",
+		"es.ccr.remote_cluster": "leader",
+	}
+	for key, val := range expectedTags {
+		if want, have := val, spans[0].Tags[key]; want != have {
+			t.Errorf("unexpected %q tag; want %q, have %q", key, want, have)
+		}
+	}
+
+	if want, have := "es/ccr.follow", spans[0].Name; want != have {
+		t.Errorf("unexpected span name; want %q, have %q", want, have)
+	}
+}
+
+func TestCCRStats(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"indices":[{"index":"logs-copy","shards":[{"operations_written":10,"failed_read_requests":1},{"operations_written":5,"failed_read_requests":0}]}]}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+	req, _ := http.NewRequest("GET", srv.URL+"/logs-copy/_ccr/stats", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "15", spans[0].Tags["es.ccr.operations_written"]; want != have {
+		t.Errorf("unexpected operations written; want %q, have %q", want, have)
+	}
+
+	if want, have := "1", spans[0].Tags["es.ccr.failed_read_requests"]; want != have {
+		t.Errorf("unexpected failed read requests; want %q, have %q", want, have)
+	}
+}
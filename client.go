@@ -0,0 +1,41 @@
+package zipkines
+
+import (
+	"net/http"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// defaultClientTimeout is the timeout of the clients returned by NewClient,
+// long enough for the heavy searches while not hanging forever on a stuck
+// node.
+const defaultClientTimeout = time.Minute
+
+// NewClient returns an HTTP client ready to call ES through a traced
+// transport. The client times out after a minute unless an existing client
+// is wrapped with WithClient, in which case its settings are kept.
+func NewClient(tracer *zipkin.Tracer, opts ...TraceOpt) *http.Client {
+	t := NewTransport(tracer, opts...)
+
+	client := &http.Client{Timeout: defaultClientTimeout}
+	if t.client != nil {
+		c := *t.client
+		client = &c
+	}
+	client.Transport = t
+	return client
+}
+
+// WithClient makes NewClient return a copy of the given client whose
+// transport is traced. The transport of the client, if any, is the one the
+// calls are passed to unless RoundTripper is passed afterwards. The given
+// client is not modified.
+func WithClient(c *http.Client) TraceOpt {
+	return func(r *Transport) {
+		r.client = c
+		if c.Transport != nil {
+			r.parent = c.Transport
+		}
+	}
+}
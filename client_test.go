@@ -0,0 +1,72 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestNewClient(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(tracer)
+	if want, have := defaultClientTimeout, client.Timeout; want != have {
+		t.Errorf("unexpected timeout; want %s, have %s", want, have)
+	}
+
+	res, err := client.Get(srv.URL + "/orders/_search")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+}
+
+func TestNewClientWrapsClient(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	var called bool
+	parent := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		called = true
+		return &http.Response{StatusCode: 200, Body: http.NoBody, Request: req}, nil
+	})
+	base := &http.Client{Timeout: 5 * time.Second, Transport: parent}
+
+	client := NewClient(tracer, WithClient(base))
+	if client == base {
+		t.Fatal("expected the wrapped client to be copied")
+	}
+
+	if want, have := base.Timeout, client.Timeout; want != have {
+		t.Errorf("unexpected timeout; want %s, have %s", want, have)
+	}
+
+	res, err := client.Get("http://localhost:9200/_cluster/health")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	if !called {
+		t.Error("expected the transport of the wrapped client to be called")
+	}
+
+	if want, have := 1, len(reporter.Flush()); want != have {
+		t.Errorf("unexpected spans number; want %d, have %d", want, have)
+	}
+}
@@ -0,0 +1,191 @@
+package zipkines
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Codec decodes the bodies of a content type into the documents the tagging
+// works with. Documents are made of the types produced by encoding/json when
+// decoding with UseNumber, i.e. maps, slices, strings, json.Number, bools and
+// nils, so the JSON pointers and the query fingerprints apply to them.
+type Codec interface {
+	// Decode returns the documents in the body, one for most formats and one
+	// per line for NDJSON.
+	Decode(body []byte) ([]interface{}, error)
+}
+
+// CodecFunc is an adapter to use ordinary functions as codecs.
+type CodecFunc func(body []byte) ([]interface{}, error)
+
+// Decode calls f(body).
+func (f CodecFunc) Decode(body []byte) ([]interface{}, error) {
+	return f(body)
+}
+
+// JSONCodec decodes a single JSON document.
+var JSONCodec Codec = CodecFunc(func(body []byte) ([]interface{}, error) {
+	doc, err := decodeJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{doc}, nil
+})
+
+// NDJSONCodec decodes one JSON document per non empty line, as in the bulk
+// and multi search requests.
+var NDJSONCodec Codec = CodecFunc(func(body []byte) ([]interface{}, error) {
+	var docs []interface{}
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		doc, err := decodeJSON(line)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+})
+
+// TextCodec decodes the whole body as a single string document, as in the
+// `_cat` APIs responses without a format.
+var TextCodec Codec = CodecFunc(func(body []byte) ([]interface{}, error) {
+	return []interface{}{string(body)}, nil
+})
+
+// sniffingTextCodec decodes the text bodies looking like JSON as JSON, as
+// some proxies and test servers label the ES responses as plain text.
+var sniffingTextCodec Codec = CodecFunc(func(body []byte) ([]interface{}, error) {
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return JSONCodec.Decode(trimmed)
+	}
+	return TextCodec.Decode(body)
+})
+
+func decodeJSON(body []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// defaultCodecs are keyed by media type, including the compatibility ones
+// sent by the official clients.
+var defaultCodecs = map[string]Codec{
+	"application/json":                       JSONCodec,
+	"application/vnd.elasticsearch+json":     JSONCodec,
+	"application/x-ndjson":                   NDJSONCodec,
+	"application/vnd.elasticsearch+x-ndjson": NDJSONCodec,
+	"text/plain":                             sniffingTextCodec,
+}
+
+// codecFor returns the codec of a Content-Type header value. Bodies without
+// a content type are assumed to be JSON as ES does.
+func (r *Transport) codecFor(contentType string) (Codec, bool) {
+	if contentType == "" {
+		return JSONCodec, true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	mediaType = strings.ToLower(mediaType)
+
+	if c, ok := r.codecs[mediaType]; ok {
+		return c, true
+	}
+	c, ok := defaultCodecs[mediaType]
+	return c, ok
+}
+
+// decodeBody decodes a body by its Content-Type, it returns false if there is
+// no codec for it or the body can not be decoded.
+func (r *Transport) decodeBody(contentType string, body []byte) ([]interface{}, bool) {
+	c, ok := r.codecFor(contentType)
+	if !ok {
+		return nil, false
+	}
+
+	docs, err := c.Decode(body)
+	if err != nil || len(docs) == 0 {
+		return nil, false
+	}
+	return docs, true
+}
+
+// jsonMediaTypes are the media types of the bodies handed to the tagging as
+// they are, unless their codec is overridden.
+var jsonMediaTypes = map[string]bool{
+	"":                                   true,
+	"application/json":                   true,
+	"application/vnd.elasticsearch+json": true,
+}
+
+// jsonBody returns a body as JSON, as the tagging parses it, by decoding it
+// with the codec of its Content-Type and encoding the documents back, one
+// per line if there are many. Text bodies, i.e. decoded as a single string,
+// are returned as they are and flagged.
+func (r *Transport) jsonBody(contentType string, body []byte) ([]byte, bool, error) {
+	var mediaType string
+	if contentType != "" {
+		mt, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, false, err
+		}
+		mediaType = strings.ToLower(mt)
+	}
+	if _, overridden := r.codecs[mediaType]; jsonMediaTypes[mediaType] && !overridden {
+		return body, false, nil
+	}
+
+	c, ok := r.codecFor(contentType)
+	if !ok {
+		return nil, false, fmt.Errorf("no codec for %q", mediaType)
+	}
+
+	docs, err := c.Decode(body)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(docs) == 1 {
+		if _, ok := docs[0].(string); ok {
+			return body, true, nil
+		}
+	}
+
+	lines := make([][]byte, 0, len(docs))
+	for _, doc := range docs {
+		line, err := json.Marshal(doc)
+		if err != nil {
+			return nil, false, err
+		}
+		lines = append(lines, line)
+	}
+	return bytes.Join(lines, []byte("\n")), false, nil
+}
+
+// WithBodyCodec registers the codec for a media type, e.g. "application/cbor",
+// overriding the default one if any. The codecs decode the response bodies
+// for all the response tagging, e.g. the hits, the shards and the error
+// envelope, as well as the request bodies for the query fingerprints. The
+// response bodies of the media types without a codec are not parsed.
+func WithBodyCodec(mediaType string, c Codec) TraceOpt {
+	return func(r *Transport) {
+		if r.codecs == nil {
+			r.codecs = map[string]Codec{}
+		}
+		r.codecs[strings.ToLower(mediaType)] = c
+	}
+}
@@ -0,0 +1,147 @@
+package zipkines
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestCodecFor(t *testing.T) {
+	transport := NewTransport(nil, WithBodyCodec("Application/CBOR", TextCodec))
+
+	testCases := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"", true},
+		{"application/json; charset=UTF-8", true},
+		{"application/vnd.elasticsearch+json; compatible-with=8", true},
+		{"application/x-ndjson", true},
+		{"text/plain", true},
+		{"application/cbor", true},
+		{"application/smile", false},
+		{";;", false},
+	}
+
+	for _, tc := range testCases {
+		if _, ok := transport.codecFor(tc.contentType); tc.expected != ok {
+			t.Errorf("unexpected codec match for %q; want %t, have %t", tc.contentType, tc.expected, ok)
+		}
+	}
+}
+
+func TestNDJSONCodec(t *testing.T) {
+	docs, err := NDJSONCodec.Decode([]byte("{\"index\":{}}\n\n{\"field\":1}\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, have := 2, len(docs); want != have {
+		t.Fatalf("unexpected docs number; want %d, have %d", want, have)
+	}
+
+	if _, err := NDJSONCodec.Decode([]byte("{\"index\":{}}\nnot json\n")); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestCustomCodecIsUsedForPointers(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/x-kv")
+		rw.Write([]byte("docs=12"))
+	}))
+	defer srv.Close()
+
+	kvCodec := CodecFunc(func(body []byte) ([]interface{}, error) {
+		doc := map[string]interface{}{}
+		for _, pair := range bytes.Split(body, []byte("&")) {
+			kv := bytes.SplitN(pair, []byte("="), 2)
+			doc[string(kv[0])] = string(kv[1])
+		}
+		return []interface{}{doc}, nil
+	})
+
+	transport := NewTransport(
+		tracer,
+		WithBodyCodec("application/x-kv", kvCodec),
+		WithResponsePointerTags("_stats", map[string]string{"es.stats.docs": "/docs"}),
+	)
+	req, _ := http.NewRequest("GET", srv.URL+"/_stats", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "12", spans[0].Tags["es.stats.docs"]; want != have {
+		t.Errorf("unexpected docs; want %q, have %q", want, have)
+	}
+}
+
+func TestCustomCodecIsUsedForTheResponseTagging(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/x-base64-json")
+		if req.URL.Path == "/missing/_search" {
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(base64.StdEncoding.EncodeToString([]byte(`{"error":{"type":"index_not_found_exception"},"status":404}`))))
+			return
+		}
+		rw.Write([]byte(base64.StdEncoding.EncodeToString([]byte(`{"_shards":{"total":3},"hits":{"total":{"value":42,"relation":"eq"}}}`))))
+	}))
+	defer srv.Close()
+
+	base64JSON := CodecFunc(func(body []byte) ([]interface{}, error) {
+		decoded, err := base64.StdEncoding.DecodeString(string(body))
+		if err != nil {
+			return nil, err
+		}
+		return JSONCodec.Decode(decoded)
+	})
+	transport := NewTransport(
+		tracer,
+		WithBodyCodec("application/x-base64-json", base64JSON),
+		WithTagTotalHits(),
+		WithTagTotalShards(),
+		WithTagErrorType(),
+	)
+	for _, path := range []string{"/logs/_search", "/missing/_search"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for key, val := range map[string]string{"es.hits.total": "42", "es.shards.total": "3"} {
+		if want, have := val, spans[0].Tags[key]; want != have {
+			t.Errorf("unexpected %q tag; want %q, have %q", key, want, have)
+		}
+	}
+
+	if want, have := "index_not_found_exception", spans[1].Tags["es.error.type"]; want != have {
+		t.Errorf("unexpected error type; want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,66 @@
+package zipkines
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// expectsContinue tells whether the request waits for ES to accept it before
+// sending its body.
+func expectsContinue(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Expect"), "100-continue")
+}
+
+// requestTap keeps a copy of a request body, up to a limit, as the parent
+// transport sends it. The parent transport might still be sending it when
+// the response arrives, hence the lock.
+type requestTap struct {
+	io.ReadCloser
+	limit int64
+
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	overflow bool
+	eof      bool
+}
+
+func (t *requestTap) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n > 0 && !t.overflow {
+		if t.limit > 0 && int64(t.buf.Len()+n) > t.limit {
+			t.overflow = true
+			t.buf = bytes.Buffer{}
+		} else {
+			t.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		t.eof = true
+	}
+	return n, err
+}
+
+// sent returns the body if it was entirely sent and kept.
+func (t *requestTap) sent() ([]byte, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.eof || t.overflow {
+		return nil, false
+	}
+	return append([]byte(nil), t.buf.Bytes()...), true
+}
+
+// tapRequestBody returns a shallow copy of the request whose body is tapped,
+// leaving its length and GetBody untouched.
+func (r *Transport) tapRequestBody(req *http.Request) (*http.Request, *requestTap) {
+	tap := &requestTap{ReadCloser: req.Body, limit: r.bodyReadLimit(-1)}
+	req = req.WithContext(req.Context())
+	req.Body = tap
+	return req, tap
+}
@@ -0,0 +1,84 @@
+package zipkines
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	atomic.AddInt64(&r.n, int64(n))
+	return n, err
+}
+
+func TestExpectContinue(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/full/_search" {
+			rw.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		received = string(body)
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	parent := http.DefaultTransport.(*http.Transport).Clone()
+	parent.ExpectContinueTimeout = 5 * time.Second
+	transport := NewTransport(tracer, RoundTripper(parent), WithTagQuery())
+
+	query := `{"query":{"match_all":{}}}`
+	for _, path := range []string{"/logs/_search", "/full/_search"} {
+		body := &countingReader{Reader: strings.NewReader(query)}
+		req, _ := http.NewRequest("POST", srv.URL+path, ioutil.NopCloser(body))
+		req.ContentLength = int64(len(query))
+		req.Header.Set("Expect", "100-continue")
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+
+		if path == "/full/_search" {
+			if want, have := int64(0), atomic.LoadInt64(&body.n); want != have {
+				t.Errorf("unexpected bytes read from the rejected body; want %d, have %d", want, have)
+			}
+		}
+	}
+
+	if want, have := query, received; want != have {
+		t.Errorf("unexpected body received; want %q, have %q", want, have)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := query, spans[0].Tags["es.query"]; want != have {
+		t.Errorf("unexpected query; want %q, have %q", want, have)
+	}
+
+	if _, ok := spans[1].Tags["es.query"]; ok {
+		t.Error("expected the query of the rejected body not to be tagged")
+	}
+}
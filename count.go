@@ -0,0 +1,25 @@
+package zipkines
+
+import (
+	"encoding/json"
+	"fmt"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+type countResponse struct {
+	Count *int `json:"count"`
+}
+
+// tagCount tags the number of documents matching a count request.
+func tagCount(span zipkin.Span, body []byte) error {
+	res := countResponse{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	if res.Count != nil {
+		span.Tag("es.count", fmt.Sprintf("%d", *res.Count))
+	}
+	return nil
+}
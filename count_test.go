@@ -0,0 +1,47 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestCountResponses(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"count":42,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagTotalHits())
+	for _, path := range []string{"/logs/_count", "/_count"} {
+		req, _ := http.NewRequest("POST", srv.URL+path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for _, span := range spans {
+		if want, have := "es/_count", span.Name; want != have {
+			t.Errorf("unexpected span name; want %q, have %q", want, have)
+		}
+
+		if want, have := "42", span.Tags["es.count"]; want != have {
+			t.Errorf("unexpected count; want %q, have %q", want, have)
+		}
+
+		if want, have := "count", span.Tags["es.operation"]; want != have {
+			t.Errorf("unexpected operation; want %q, have %q", want, have)
+		}
+	}
+}
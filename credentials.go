@@ -0,0 +1,42 @@
+package zipkines
+
+import (
+	"net/url"
+	"strings"
+)
+
+// credentialQueryParams are the query params which might carry credentials,
+// they are never tagged.
+var credentialQueryParams = map[string]bool{
+	"access_token":  true,
+	"api_key":       true,
+	"apikey":        true,
+	"auth":          true,
+	"authorization": true,
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+}
+
+// isCredentialQueryParam tells whether a query param might carry
+// credentials, regardless of its case.
+func isCredentialQueryParam(key string) bool {
+	return credentialQueryParams[strings.ToLower(key)]
+}
+
+// sanitizedURL returns the URL without its userinfo and without the query
+// params which might carry credentials, to be safely tagged.
+func sanitizedURL(u *url.URL) string {
+	sanitized := *u
+	sanitized.User = nil
+	if u.RawQuery != "" {
+		params := u.Query()
+		for key := range params {
+			if isCredentialQueryParam(key) {
+				params.Del(key)
+			}
+		}
+		sanitized.RawQuery = params.Encode()
+	}
+	return sanitized.String()
+}
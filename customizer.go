@@ -0,0 +1,21 @@
+package zipkines
+
+import (
+	"net/http"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// SpanCustomizer is called with the span of a request once the round trip is
+// done. The response is nil if the round trip failed with err.
+type SpanCustomizer func(span zipkin.Span, req *http.Request, res *http.Response, err error)
+
+// WithSpanCustomizer calls the customizer for every traced request after the
+// round trip and before the span is finished, so applications can add their
+// own tags, e.g. the tenant or a feature flag. The response body must not be
+// read as the caller has not read it yet.
+func WithSpanCustomizer(c SpanCustomizer) TraceOpt {
+	return func(r *Transport) {
+		r.spanCustomizer = c
+	}
+}
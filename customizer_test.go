@@ -0,0 +1,70 @@
+package zipkines
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestSpanCustomizer(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-Shard-Hint", "3")
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	customizer := func(span zipkin.Span, req *http.Request, res *http.Response, err error) {
+		span.Tag("tenant", req.Header.Get("X-Tenant"))
+		if err != nil {
+			span.Tag("custom.error", err.Error())
+			return
+		}
+		span.Tag("shard.hint", res.Header.Get("X-Shard-Hint"))
+	}
+
+	transport := NewTransport(tracer, WithSpanCustomizer(customizer))
+	req, _ := http.NewRequest("GET", srv.URL+"/logs/_search", nil)
+	req.Header.Set("X-Tenant", "acme")
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	failing := NewTransport(tracer, WithSpanCustomizer(customizer), RoundTripper(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})))
+	req, _ = http.NewRequest("GET", srv.URL+"/logs/_search", nil)
+	req.Header.Set("X-Tenant", "acme")
+	if _, err := failing.RoundTrip(req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expectedTags := []map[string]string{
+		{"tenant": "acme", "shard.hint": "3"},
+		{"tenant": "acme", "custom.error": "connection refused"},
+	}
+	for i, tags := range expectedTags {
+		for key, val := range tags {
+			if want, have := val, spans[i].Tags[key]; want != have {
+				t.Errorf("unexpected %q tag for span %d; want %q, have %q", key, i, want, have)
+			}
+		}
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
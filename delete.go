@@ -0,0 +1,46 @@
+package zipkines
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// tagIndicesDeletion tags the target of `DELETE /{indices}` requests when it
+// addresses more than one index, either through a comma separated list or
+// through wildcards.
+func tagIndicesDeletion(span zipkin.Span, path string, annotate bool) {
+	expr := strings.Trim(path, "/")
+	if expr == "" || strings.Contains(expr, "/") {
+		return
+	}
+
+	indices := strings.Split(expr, ",")
+	wildcard := false
+	for _, index := range indices {
+		if index == "_all" || strings.Contains(index, "*") {
+			wildcard = true
+		}
+	}
+
+	if !wildcard && len(indices) == 1 {
+		return
+	}
+
+	span.Tag("es.delete.indices", expr)
+	span.Tag("es.delete.indices.count", fmt.Sprintf("%d", len(indices)))
+
+	if wildcard && annotate {
+		span.Annotate(time.Now(), "es.destructive_wildcard")
+	}
+}
+
+// WithDestructiveWildcardAnnotation annotates the spans of index deletions
+// targeting wildcard expressions (or `_all`) as destructive operations.
+func WithDestructiveWildcardAnnotation() TraceOpt {
+	return func(r *Transport) {
+		r.opts.AnnotateDestructiveWildcards = true
+	}
+}
@@ -0,0 +1,61 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestWildcardIndicesDeletion(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"acknowledged":true}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithDestructiveWildcardAnnotation())
+
+	testCases := []struct {
+		path               string
+		expectedExpr       string
+		expectedCount      string
+		expectedAnnotation bool
+	}{
+		{"/logs-*,metrics", "logs-*,metrics", "2", true},
+		{"/logs,metrics", "logs,metrics", "2", false},
+		{"/logs", "", "", false},
+		{"/logs/_doc/1", "", "", false},
+	}
+
+	for _, tc := range testCases {
+		req, _ := http.NewRequest("DELETE", srv.URL+tc.path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		spans := reporter.Flush()
+		if want, have := 1, len(spans); want != have {
+			t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+		}
+
+		if want, have := tc.expectedExpr, spans[0].Tags["es.delete.indices"]; want != have {
+			t.Errorf("unexpected indices expression; want %q, have %q", want, have)
+		}
+
+		if want, have := tc.expectedCount, spans[0].Tags["es.delete.indices.count"]; want != have {
+			t.Errorf("unexpected indices count; want %q, have %q", want, have)
+		}
+
+		if want, have := tc.expectedAnnotation, len(spans[0].Annotations) == 1; want != have {
+			t.Errorf("unexpected destructive annotation for %q; want %t, have %t", tc.path, want, have)
+		}
+	}
+}
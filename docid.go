@@ -0,0 +1,201 @@
+package zipkines
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// DocIDPolicy defines how document IDs are treated when they would be part
+// of a tag, e.g. in the path or the query.
+type DocIDPolicy int
+
+const (
+	// DocIDKeep tags the document IDs as they are.
+	DocIDKeep DocIDPolicy = iota
+	// DocIDHash replaces the document IDs by a truncated SHA-256 hash so
+	// the calls on the same document can still be correlated.
+	DocIDHash
+	// DocIDDrop replaces the document IDs by a placeholder.
+	DocIDDrop
+)
+
+const redactedDocID = "{id}"
+
+// docIDEndpoints are the endpoints addressing a document by its ID in the
+// path as in `/{index}/{endpoint}/{id}`.
+var docIDEndpoints = map[string]bool{
+	"_doc":         true,
+	"_create":      true,
+	"_update":      true,
+	"_source":      true,
+	"_explain":     true,
+	"_termvectors": true,
+}
+
+func (p DocIDPolicy) redact(id string) string {
+	switch p {
+	case DocIDHash:
+		return shortHash(id)
+	case DocIDDrop:
+		return redactedDocID
+	}
+	return id
+}
+
+// shortHash returns a truncated SHA-256 hash of the value, short enough to
+// be tagged but still useful to correlate equal values.
+func shortHash(val string) string {
+	sum := sha256.Sum256([]byte(val))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// redactPath redacts the document ID in a path, including the legacy typed
+// paths like `/{index}/{type}/{id}`.
+func (p DocIDPolicy) redactPath(path string) string {
+	if p == DocIDKeep {
+		return path
+	}
+
+	pieces := strings.Split(path, "/")
+	// the path is absolute, hence pieces[0] is empty
+	if len(pieces) < 4 || pieces[1] == "" || pieces[1][:1] == "_" || pieces[3] == "" {
+		return path
+	}
+
+	if docIDEndpoints[pieces[2]] || (pieces[2] != "" && pieces[2][:1] != "_" && pieces[3][:1] != "_") {
+		pieces[3] = p.redact(pieces[3])
+	}
+	return strings.Join(pieces, "/")
+}
+
+// redactBody redacts the document IDs in a JSON or NDJSON body, i.e. the
+// `_id` fields (as in bulk metadata and mget docs) and the `ids` lists (as
+// in mget and the ids query). Lines which are not JSON are dropped as they
+// might hold IDs nonetheless. The keys order and the numbers are kept as
+// they are.
+func (p DocIDPolicy) redactBody(body []byte) []byte {
+	if p == DocIDKeep {
+		return body
+	}
+
+	var redacted [][]byte
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		buf := &bytes.Buffer{}
+		if err := p.redactValue(dec, buf, ""); err != nil {
+			continue
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			// trailing values
+			continue
+		}
+		redacted = append(redacted, buf.Bytes())
+	}
+	return bytes.Join(redacted, []byte("\n"))
+}
+
+// redactValue copies the next value of the decoder to the buffer, redacting
+// the strings of the `_id` keys and of the `ids` lists.
+func (p DocIDPolicy) redactValue(dec *json.Decoder, buf *bytes.Buffer, key string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			buf.WriteByte('{')
+			for i := 0; dec.More(); i++ {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				k, _ := keyTok.(string)
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				writeJSON(buf, k)
+				buf.WriteByte(':')
+
+				childKey := k
+				if key == "ids" && k == "values" {
+					// the ids query holds the list under values
+					childKey = "ids"
+				}
+				if err := p.redactValue(dec, buf, childKey); err != nil {
+					return err
+				}
+			}
+			buf.WriteByte('}')
+		} else {
+			elemKey := ""
+			if key == "ids" {
+				elemKey = "_id"
+			}
+			buf.WriteByte('[')
+			for i := 0; dec.More(); i++ {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				if err := p.redactValue(dec, buf, elemKey); err != nil {
+					return err
+				}
+			}
+			buf.WriteByte(']')
+		}
+		// the closing delimiter
+		_, err := dec.Token()
+		return err
+	case string:
+		if key == "_id" {
+			v = p.redact(v)
+		}
+		writeJSON(buf, v)
+	case json.Number:
+		buf.WriteString(v.String())
+	default:
+		writeJSON(buf, v)
+	}
+	return nil
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	b, _ := json.Marshal(v)
+	buf.Write(b)
+}
+
+// bracketed matches the bracketed values of the ES error reasons, e.g.
+// `[1]: version conflict, current version [2] is different than the one
+// provided [1]` or `[_doc][1]: document missing`.
+var bracketed = regexp.MustCompile(`\[([^\[\]]+)\]`)
+
+// redactReason redacts the bracketed values of an error reason as any of
+// them might be a document ID.
+func (p DocIDPolicy) redactReason(reason string) string {
+	if p == DocIDKeep {
+		return reason
+	}
+	return bracketed.ReplaceAllStringFunc(reason, func(m string) string {
+		return "[" + p.redact(m[1:len(m)-1]) + "]"
+	})
+}
+
+// WithDocIDRedaction sets how the document IDs are treated anywhere they
+// would be tagged: the path, the bulk metadata, the mget docs, the ids
+// queries and the error reasons, whose bracketed values are all redacted.
+func WithDocIDRedaction(p DocIDPolicy) TraceOpt {
+	return func(r *Transport) {
+		r.opts.DocIDPolicy = p
+	}
+}
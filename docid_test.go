@@ -0,0 +1,148 @@
+package zipkines
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestRedactPath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"/users/_doc/jane", "/users/_doc/{id}"},
+		{"/users/_update/jane", "/users/_update/{id}"},
+		{"/users/user/jane", "/users/user/{id}"},
+		{"/users/user/jane/_update", "/users/user/{id}/_update"},
+		{"/users/_doc", "/users/_doc"},
+		{"/users/_search", "/users/_search"},
+		{"/users/user/_search", "/users/user/_search"},
+		{"/_cluster/health/users", "/_cluster/health/users"},
+	}
+
+	for _, tc := range testCases {
+		if want, have := tc.expected, DocIDDrop.redactPath(tc.path); want != have {
+			t.Errorf("unexpected redacted path; want %q, have %q", want, have)
+		}
+	}
+
+	if want, have := "/users/_doc/jane", DocIDKeep.redactPath("/users/_doc/jane"); want != have {
+		t.Errorf("unexpected redacted path; want %q, have %q", want, have)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	testCases := []struct {
+		body     string
+		expected string
+	}{
+		{`{"docs":[{"_index":"users","_id":"jane"}]}`, `{"docs":[{"_index":"users","_id":"{id}"}]}`},
+		{`{"size":10,"query":{"term":{"account":9007199254740993}}}`, `{"size":10,"query":{"term":{"account":9007199254740993}}}`},
+		{`{"ids":["jane"]} {}`, ``},
+		{`not json`, ``},
+		{`{"ids":["jane","john"]}`, `{"ids":["{id}","{id}"]}`},
+		{`{"query":{"ids":{"values":["jane"]}}}`, `{"query":{"ids":{"values":["{id}"]}}}`},
+		{"{\"index\":{\"_id\":\"jane\"}}\n{\"name\":\"jane\"}\n", "{\"index\":{\"_id\":\"{id}\"}}\n{\"name\":\"jane\"}"},
+	}
+
+	for _, tc := range testCases {
+		if want, have := tc.expected, string(DocIDDrop.redactBody([]byte(tc.body))); want != have {
+			t.Errorf("unexpected redacted body; want %q, have %q", want, have)
+		}
+	}
+}
+
+func TestDocIDsAreHashedInTags(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagQuery(), WithDocIDRedaction(DocIDHash))
+	req, _ := http.NewRequest("PUT", srv.URL+"/users/_doc/jane", bytes.NewBufferString(`{"_id":"jane"}`))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for _, key := range []string{"http.path", "es.query"} {
+		val := spans[0].Tags[key]
+		if strings.Contains(val, "jane") || !strings.Contains(val, "sha256:") {
+			t.Errorf("expected hashed document ID in %q, have %q", key, val)
+		}
+	}
+}
+
+func TestRedactReason(t *testing.T) {
+	reason := "[jane]: version conflict, current version [2] is different than the one provided [1]"
+	if want, have := "[{id}]: version conflict, current version [{id}] is different than the one provided [{id}]", DocIDDrop.redactReason(reason); want != have {
+		t.Errorf("unexpected redacted reason; want %q, have %q", want, have)
+	}
+
+	if want, have := reason, DocIDKeep.redactReason(reason); want != have {
+		t.Errorf("unexpected redacted reason; want %q, have %q", want, have)
+	}
+}
+
+func TestDocIDsAreRedactedInErrorReasons(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/users/_search" {
+			rw.Write([]byte(`{"_shards":{"total":2,"successful":1,"failed":1,"failures":[{"index":"users",` +
+				`"reason":{"type":"document_missing_exception","reason":"[_doc][jane]: document missing"}}]}}`))
+			return
+		}
+		rw.WriteHeader(http.StatusConflict)
+		rw.Write([]byte(`{"error":{"root_cause":[{"type":"version_conflict_engine_exception",` +
+			`"reason":"[jane]: version conflict, current version [2] is different than the one provided [1]"}],` +
+			`"type":"version_conflict_engine_exception",` +
+			`"reason":"[jane]: version conflict, current version [2] is different than the one provided [1]"},"status":409}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagErrorType(), WithTagTotalShards(), WithDocIDRedaction(DocIDDrop))
+	for _, path := range []string{"/users/_doc/jane?if_seq_no=1&if_primary_term=1", "/users/_search"} {
+		req, _ := http.NewRequest("PUT", srv.URL+path, bytes.NewBufferString(`{"name":"jane"}`))
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for _, key := range []string{"es.error.reason", "es.error.root_cause.reason"} {
+		if want, have := "[{id}]: version conflict, current version [{id}] is different than the one provided [{id}]", spans[0].Tags[key]; want != have {
+			t.Errorf("unexpected %q tag; want %q, have %q", key, want, have)
+		}
+	}
+
+	if want, have := "[{id}][{id}]: document missing", spans[1].Tags["es.shards.failure.1.reason"]; want != have {
+		t.Errorf("unexpected shard failure reason; want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,116 @@
+package zipkines
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// finishingBody finishes the span of a request once its response body is
+// read to the end or closed, whichever comes first, so the span covers the
+// download of the response.
+type finishingBody struct {
+	io.ReadCloser
+	// size is the announced length of the body, negative if unknown.
+	size   int64
+	once   sync.Once
+	finish func(aborted bool)
+
+	// mu guards read as the body can be closed while it is being read.
+	mu   sync.Mutex
+	read int64
+}
+
+func (b *finishingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	b.read += int64(n)
+	b.mu.Unlock()
+	if err == io.EOF {
+		b.done(false)
+	}
+	return n, err
+}
+
+func (b *finishingBody) Close() error {
+	err := b.ReadCloser.Close()
+	// decoders stop reading at the end of the value without seeing the EOF,
+	// hence a body of known length read entirely is not aborted either.
+	b.mu.Lock()
+	read := b.read
+	b.mu.Unlock()
+	b.done(b.size < 0 || read < b.size)
+	return err
+}
+
+func (b *finishingBody) done(aborted bool) {
+	b.once.Do(func() {
+		b.finish(aborted)
+	})
+}
+
+// tappedBody keeps a copy of the bytes of a response body read by the caller,
+// up to a limit, so the tags can be extracted from it once it is read.
+type tappedBody struct {
+	io.ReadCloser
+	// limit is the maximum number of bytes kept, non positive means no
+	// limit.
+	limit int64
+
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (b *tappedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n > 0 && !b.overflow {
+		if b.limit > 0 && int64(b.buf.Len()+n) > b.limit {
+			b.overflow = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	return n, err
+}
+
+// body returns the bytes read so far and whether they were all kept. It can
+// be called from the finishingBody callback while the caller still reads
+// the body, e.g. when closing it from another goroutine.
+func (b *tappedBody) body() ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...), !b.overflow
+}
+
+// WithFinishOnBodyClose defers the finish of the spans until the caller reads
+// the response body to the end or closes it, so they cover the download of
+// the response and not only the time to the headers. Bodies closed before
+// being read to the end, e.g. when the caller only needs the beginning of a
+// large response, are tagged "es.response.aborted". As mandated by net/http
+// callers must close the response bodies, otherwise their spans are never
+// reported.
+func WithFinishOnBodyClose() TraceOpt {
+	return func(r *Transport) {
+		r.finishOnBodyClose = true
+	}
+}
+
+// WithLazyResponseParsing extracts the tags from the successful response
+// bodies as the caller reads them, instead of reading them upfront in
+// RoundTrip, so the response timing is left untouched and the bodies are not
+// held twice in memory until the caller is done with them. Only the bytes up
+// to the limits set by WithMaxChunkedBodyRead and WithMaxBodyRead are kept,
+// regardless of the body length. The spans are finished as with
+// WithFinishOnBodyClose and the response values of ContextWithResponseMeta
+// are available once the body is read. Bodies closed before being read to
+// the end are not parsed.
+func WithLazyResponseParsing() TraceOpt {
+	return func(r *Transport) {
+		r.lazyResponseParsing = true
+	}
+}
@@ -0,0 +1,205 @@
+package zipkines
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestFinishOnBodyClose(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	payload := strings.Repeat(`{"message":"hello"}`, 1000)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(payload))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithFinishOnBodyClose())
+
+	req, _ := http.NewRequest("GET", srv.URL+"/logs/_doc/1", nil)
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, have := 0, len(reporter.Flush()); want != have {
+		t.Fatalf("unexpected spans number before reading the body; want %d, have %d", want, have)
+	}
+
+	body, _ := ioutil.ReadAll(res.Body)
+	if want, have := len(payload), len(body); want != have {
+		t.Errorf("unexpected body length; want %d, have %d", want, have)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number after reading the body; want %d, have %d", want, have)
+	}
+	if _, ok := spans[0].Tags["es.response.aborted"]; ok {
+		t.Error("expected the body read to the end not to be tagged as aborted")
+	}
+
+	res.Body.Close()
+	if want, have := 0, len(reporter.Flush()); want != have {
+		t.Errorf("unexpected spans number after closing the body; want %d, have %d", want, have)
+	}
+
+	req, _ = http.NewRequest("GET", srv.URL+"/logs/_doc/1", nil)
+	res, err = transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Read(make([]byte, 10))
+	res.Body.Close()
+
+	spans = reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number after aborting the body; want %d, have %d", want, have)
+	}
+	if want, have := "true", spans[0].Tags["es.response.aborted"]; want != have {
+		t.Errorf("unexpected aborted tag; want %q, have %q", want, have)
+	}
+}
+
+func TestLazyResponseParsing(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"took":3,"_shards":{"total":5},"hits":{"total":{"value":42,"relation":"eq"}}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagTotalHits(), WithTagTotalShards(), WithLazyResponseParsing())
+
+	ctx := ContextWithResponseMeta(context.Background())
+	req, _ := http.NewRequest("GET", srv.URL+"/logs/_search", nil)
+	res, err := transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, have := 0, len(reporter.Flush()); want != have {
+		t.Fatalf("unexpected spans number before reading the body; want %d, have %d", want, have)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&struct{}{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for tag, value := range map[string]string{
+		"es.hits.total":       "42",
+		"es.shards.total":     "5",
+		"es.response.aborted": "",
+	} {
+		if want, have := value, spans[0].Tags[tag]; want != have {
+			t.Errorf("unexpected %s; want %q, have %q", tag, want, have)
+		}
+	}
+
+	if want, have := 3, ResponseMetaFromContext(ctx).Took; want != have {
+		t.Errorf("unexpected took; want %d, have %d", want, have)
+	}
+}
+
+func TestLazyResponseParsingOverLimit(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"hits":{"total":42,"hits":[{"_id":"1"},{"_id":"2"}]}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagTotalHits(), WithLazyResponseParsing(), WithMaxChunkedBodyRead(16))
+	req, _ := http.NewRequest("GET", srv.URL+"/logs/_search", nil)
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "", spans[0].Tags["es.hits.total"]; want != have {
+		t.Errorf("unexpected hits total; want %q, have %q", want, have)
+	}
+}
+
+func TestTappedBodyCanBeReadConcurrently(t *testing.T) {
+	tap := &tappedBody{ReadCloser: ioutil.NopCloser(strings.NewReader(strings.Repeat("a", 4096)))}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p := make([]byte, 16)
+		for {
+			if _, err := tap.Read(p); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		tap.body()
+	}
+	<-done
+
+	body, complete := tap.body()
+	if !complete {
+		t.Fatal("expected the body to be complete")
+	}
+
+	if want, have := 4096, len(body); want != have {
+		t.Errorf("unexpected body length; want %d, have %d", want, have)
+	}
+}
+
+func TestFinishingBodyCanBeClosedWhileRead(t *testing.T) {
+	finished := make(chan bool, 2)
+	body := &finishingBody{
+		ReadCloser: ioutil.NopCloser(strings.NewReader(strings.Repeat("a", 4096))),
+		size:       4096,
+		finish:     func(aborted bool) { finished <- aborted },
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p := make([]byte, 16)
+		for {
+			if _, err := body.Read(p); err != nil {
+				return
+			}
+		}
+	}()
+
+	body.Close()
+	<-done
+
+	if want, have := 1, len(finished); want != have {
+		t.Errorf("unexpected number of finishes; want %d, have %d", want, have)
+	}
+}
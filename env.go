@@ -0,0 +1,83 @@
+package zipkines
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// envBoolOpts are the tagging options toggled by boolean environment
+// variables.
+var envBoolOpts = []struct {
+	name string
+	set  func(o *TraceOpts, enabled bool)
+}{
+	{"ZIPKIN_ES_TAG_QUERY", func(o *TraceOpts, enabled bool) { o.TagQuery = enabled }},
+	{"ZIPKIN_ES_TAG_HITS", func(o *TraceOpts, enabled bool) { o.TagTotalHits = enabled }},
+	{"ZIPKIN_ES_TAG_SHARDS", func(o *TraceOpts, enabled bool) { o.TagTotalShards = enabled }},
+	{"ZIPKIN_ES_TAG_ERROR_TYPE", func(o *TraceOpts, enabled bool) { o.TagErrorType = enabled }},
+	{"ZIPKIN_ES_TAG_HOST", func(o *TraceOpts, enabled bool) { o.TagHost = enabled }},
+}
+
+// envOpts returns the options set by the environment variables found by
+// lookup.
+func envOpts(lookup func(string) (string, bool)) ([]TraceOpt, error) {
+	var opts []TraceOpt
+	for _, e := range envBoolOpts {
+		val, ok := lookup(e.name)
+		if !ok {
+			continue
+		}
+
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", e.name, err)
+		}
+
+		set := e.set
+		opts = append(opts, func(r *Transport) {
+			set(&r.opts, enabled)
+		})
+	}
+
+	if val, ok := lookup("ZIPKIN_ES_WHITELIST_PARAMS"); ok {
+		var params []string
+		for _, p := range strings.Split(val, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				params = append(params, p)
+			}
+		}
+		opts = append(opts, WithWhitelistQueryParams(params...))
+	}
+
+	if val, ok := lookup("ZIPKIN_ES_MAX_BODY_READ"); ok {
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ZIPKIN_ES_MAX_BODY_READ: %v", err)
+		}
+		opts = append(opts, WithMaxBodyRead(n))
+	}
+
+	return opts, nil
+}
+
+// NewTransportFromEnv returns a Transport as NewTransport does, with the
+// given options overridden by the ones set in the environment so operators
+// can change the tagging without redeploying:
+//
+//	ZIPKIN_ES_TAG_QUERY, ZIPKIN_ES_TAG_HITS, ZIPKIN_ES_TAG_SHARDS,
+//	ZIPKIN_ES_TAG_ERROR_TYPE, ZIPKIN_ES_TAG_HOST: booleans, e.g. "true" or "0"
+//	ZIPKIN_ES_WHITELIST_PARAMS: comma separated query params, e.g. "routing,timeout"
+//	ZIPKIN_ES_MAX_BODY_READ: bytes, as in WithMaxBodyRead
+//
+// An error is returned if any of them is invalid.
+func NewTransportFromEnv(tracer *zipkin.Tracer, opts ...TraceOpt) (*Transport, error) {
+	env, err := envOpts(os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	return NewTransport(tracer, append(append([]TraceOpt(nil), opts...), env...)...), nil
+}
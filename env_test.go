@@ -0,0 +1,44 @@
+package zipkines
+
+import (
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestNewTransportFromEnv(t *testing.T) {
+	tracer, _ := zipkin.NewTracer(recorder.NewReporter())
+
+	t.Setenv("ZIPKIN_ES_TAG_QUERY", "true")
+	t.Setenv("ZIPKIN_ES_TAG_HITS", "0")
+	t.Setenv("ZIPKIN_ES_WHITELIST_PARAMS", "routing, timeout")
+	t.Setenv("ZIPKIN_ES_MAX_BODY_READ", "1024")
+
+	transport, err := NewTransportFromEnv(tracer, WithTagTotalHits(), WithTagErrorType())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts := transport.Options()
+	if !opts.TagQuery || opts.TagTotalHits || !opts.TagErrorType {
+		t.Errorf("unexpected tagging options: %+v", opts)
+	}
+
+	if want, have := 2, len(opts.WhitelistQueryParams); want != have {
+		t.Fatalf("unexpected whitelisted params number; want %d, have %d", want, have)
+	}
+
+	if want, have := "timeout", opts.WhitelistQueryParams[1]; want != have {
+		t.Errorf("unexpected whitelisted param; want %q, have %q", want, have)
+	}
+
+	if want, have := int64(1024), transport.maxBodyRead; want != have {
+		t.Errorf("unexpected max body read; want %d, have %d", want, have)
+	}
+
+	t.Setenv("ZIPKIN_ES_TAG_QUERY", "sometimes")
+	if _, err := NewTransportFromEnv(tracer); err == nil {
+		t.Error("expected error for invalid boolean")
+	}
+}
@@ -0,0 +1,136 @@
+package zipkines
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// maxErrorReasonLength is the maximum length in bytes of the tagged error
+// reasons, which might echo big chunks of the query.
+const maxErrorReasonLength = 256
+
+type errorCause struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// errorResponse is the error returned by ES, either in the standard envelope
+// `{"error":{"type":...,"reason":...,"root_cause":[...]},"status":400}` or
+// as a bare cause, as some proxies and the legacy versions do.
+type errorResponse struct {
+	errorCause
+	RootCause []errorCause `json:"root_cause"`
+}
+
+// parseErrorResponse parses an error response body. The envelope error is a
+// plain string in some legacy versions, it is then taken as the reason.
+func parseErrorResponse(body []byte) (errorResponse, error) {
+	envelope := struct {
+		errorCause
+		Error json.RawMessage `json:"error"`
+	}{}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return errorResponse{}, err
+	}
+
+	res := errorResponse{errorCause: envelope.errorCause}
+	if len(envelope.Error) == 0 {
+		return res, nil
+	}
+
+	var reason string
+	if err := json.Unmarshal(envelope.Error, &reason); err == nil {
+		res.Reason = reason
+		return res, nil
+	}
+
+	if err := json.Unmarshal(envelope.Error, &res); err != nil {
+		return errorResponse{}, err
+	}
+	return res, nil
+}
+
+// tagErrorResponse tags the error type, falling back to the status code, as
+// "error" and "es.error.type", the truncated reason as "es.error.reason" and
+// the first root cause, if any, as "es.error.root_cause.type" and
+// "es.error.root_cause.reason". The reasons are redacted according to the
+// document ID policy.
+func tagErrorResponse(span zipkin.Span, res *http.Response, resErr errorResponse, maxLength int, ids DocIDPolicy) {
+	reasonLength := maxErrorReasonLength
+	if maxLength > 0 && maxLength < reasonLength {
+		reasonLength = maxLength
+	}
+
+	if resErr.Type == "" {
+		zipkin.TagError.Set(span, fmt.Sprintf("%d", res.StatusCode))
+	} else {
+		errType := safeTagValue(resErr.Type, maxLength)
+		zipkin.TagError.Set(span, errType)
+		span.Tag("es.error.type", errType)
+	}
+
+	if resErr.Reason != "" {
+		span.Tag("es.error.reason", safeTagValue(ids.redactReason(resErr.Reason), reasonLength))
+	}
+
+	if len(resErr.RootCause) > 0 {
+		cause := resErr.RootCause[0]
+		if cause.Type != "" {
+			span.Tag("es.error.root_cause.type", safeTagValue(cause.Type, maxLength))
+		}
+		if cause.Reason != "" {
+			span.Tag("es.error.root_cause.reason", safeTagValue(ids.redactReason(cause.Reason), reasonLength))
+		}
+	}
+}
+
+// ErrorPolicy tells whether a non successful response status code is an
+// error for the request, e.g. a 404 might just mean the document does not
+// exist.
+type ErrorPolicy func(req *http.Request, status int) bool
+
+// notFoundOperations are the operations for which a 404 is a plain answer.
+var notFoundOperations = map[string]bool{
+	"get":           true,
+	"get_source":    true,
+	"exists":        true,
+	"exists_source": true,
+}
+
+// DefaultErrorPolicy considers every non successful status code as an error
+// but the 404 of the HEAD requests, which check the existence of something,
+// and of the document gets.
+func DefaultErrorPolicy(req *http.Request, status int) bool {
+	return notFoundIsError(req, status, operationName)
+}
+
+// defaultErrorPolicy is DefaultErrorPolicy with the operations named by the
+// classifiers of the transport.
+func (r *Transport) defaultErrorPolicy(req *http.Request, status int) bool {
+	return notFoundIsError(req, status, r.operationName)
+}
+
+func notFoundIsError(req *http.Request, status int, operationName func(method, path string) (string, bool)) bool {
+	if status != http.StatusNotFound {
+		return true
+	}
+	if req.Method == "HEAD" {
+		return false
+	}
+	operation, ok := operationName(req.Method, req.URL.Path)
+	return !ok || !notFoundOperations[operation]
+}
+
+// WithErrorPolicy replaces DefaultErrorPolicy as the policy telling which
+// non successful status codes tag the span as an error. The responses which
+// are not errors are not parsed for the error type.
+func WithErrorPolicy(policy ErrorPolicy) TraceOpt {
+	return func(r *Transport) {
+		if policy != nil {
+			r.errorPolicy = policy
+		}
+	}
+}
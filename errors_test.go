@@ -0,0 +1,155 @@
+package zipkines
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestErrorEnvelopeIsTagged(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	longReason := strings.Repeat("x", 300)
+	responses := map[string]string{
+		"/envelope/_search": `{"error":{"root_cause":[{"type":"query_shard_exception","reason":"failed to create query"}],` +
+			`"type":"search_phase_execution_exception","reason":"` + longReason + `"},"status":400}`,
+		"/legacy/_search": `{"error":"IndexMissingException[[legacy] missing]","status":404}`,
+		"/bare/_search":   `{"type":"illegal_argument_exception","reason":"bad"}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(responses[req.URL.Path]))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagErrorType())
+	for _, path := range []string{"/envelope/_search", "/legacy/_search", "/bare/_search"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 3, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expectedTags := []map[string]string{
+		{
+			"error":                      "search_phase_execution_exception",
+			"es.error.type":              "search_phase_execution_exception",
+			"es.error.reason":            longReason[:maxErrorReasonLength],
+			"es.error.root_cause.type":   "query_shard_exception",
+			"es.error.root_cause.reason": "failed to create query",
+		},
+		{
+			"error":           "400",
+			"es.error.type":   "",
+			"es.error.reason": "IndexMissingException[[legacy] missing]",
+		},
+		{
+			"error":           "illegal_argument_exception",
+			"es.error.type":   "illegal_argument_exception",
+			"es.error.reason": "bad",
+		},
+	}
+	for i, tags := range expectedTags {
+		for key, val := range tags {
+			if want, have := val, spans[i].Tags[key]; want != have {
+				t.Errorf("unexpected %q tag for span %d; want %q, have %q", key, i, want, have)
+			}
+		}
+	}
+}
+
+func TestNonJSONErrorBodyIsHandedToTheCaller(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "text/html")
+		rw.WriteHeader(http.StatusBadGateway)
+		rw.Write([]byte(`<html><body><h1>502 Bad Gateway</h1></body></html>`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithLogger(discardLogger), WithTagErrorType())
+	req, _ := http.NewRequest("GET", srv.URL+"/logs/_search", nil)
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+
+	if want, have := http.StatusBadGateway, res.StatusCode; want != have {
+		t.Errorf("unexpected status code; want %d, have %d", want, have)
+	}
+
+	body, _ := ioutil.ReadAll(res.Body)
+	if want, have := `<html><body><h1>502 Bad Gateway</h1></body></html>`, string(body); want != have {
+		t.Errorf("unexpected body; want %q, have %q", want, have)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "502", spans[0].Tags["error"]; want != have {
+		t.Errorf("unexpected error tag; want %q, have %q", want, have)
+	}
+}
+
+func TestErrorPolicy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+		rw.Write([]byte(`{"_index":"orders","_id":"1","found":false}`))
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		opts     []TraceOpt
+		path     string
+		expected string
+	}{
+		{nil, "/orders/_doc/1", ""},
+		{nil, "/orders/_source/1", ""},
+		{nil, "/missing/_search", "404"},
+		{[]TraceOpt{WithErrorPolicy(func(*http.Request, int) bool { return true })}, "/orders/_doc/1", "404"},
+	}
+
+	for _, tc := range testCases {
+		reporter := recorder.NewReporter()
+		tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+		transport := NewTransport(tracer, tc.opts...)
+		req, _ := http.NewRequest("GET", srv.URL+tc.path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+
+		spans := reporter.Flush()
+		if want, have := 1, len(spans); want != have {
+			t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+		}
+
+		if want, have := tc.expected, spans[0].Tags["error"]; want != have {
+			t.Errorf("unexpected error for %q; want %q, have %q", tc.path, want, have)
+		}
+
+		if want, have := "404", spans[0].Tags["http.status_code"]; want != have {
+			t.Errorf("unexpected status code for %q; want %q, have %q", tc.path, want, have)
+		}
+	}
+}
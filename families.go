@@ -0,0 +1,87 @@
+package zipkines
+
+import (
+	"net/http"
+	"strings"
+)
+
+// APIFamily groups the ES APIs by their purpose so they can be traced
+// differently as a whole.
+type APIFamily string
+
+const (
+	// APIFamilyAdmin holds the cluster, nodes, tasks and snapshot APIs.
+	APIFamilyAdmin APIFamily = "admin"
+	// APIFamilyCat holds the `_cat` APIs.
+	APIFamilyCat APIFamily = "cat"
+	// APIFamilySecurity holds the `_security` APIs.
+	APIFamilySecurity APIFamily = "security"
+	// APIFamilyIngest holds the ingest pipeline and enrich policy APIs.
+	APIFamilyIngest APIFamily = "ingest"
+)
+
+// operationFamilies maps the namespace of the canonical operation names to
+// their family.
+var operationFamilies = map[string]APIFamily{
+	"cluster":  APIFamilyAdmin,
+	"nodes":    APIFamilyAdmin,
+	"tasks":    APIFamilyAdmin,
+	"snapshot": APIFamilyAdmin,
+	"cat":      APIFamilyCat,
+	"security": APIFamilySecurity,
+	"ingest":   APIFamilyIngest,
+	"enrich":   APIFamilyIngest,
+}
+
+// operationFamily returns the family of an operation or false if it is not
+// classified in any of them.
+func operationFamily(operation string) (APIFamily, bool) {
+	namespace := strings.SplitN(operation, ".", 2)[0]
+	family, ok := operationFamilies[namespace]
+	return family, ok
+}
+
+// familyTracing tells whether the request gets a span, whether it gets more
+// than the minimal tags and whether its bodies can be read according to its
+// API family. The bodies of the security APIs, see isSecurityPath, are never
+// read as they carry credentials.
+func (r *Transport) familyTracing(req *http.Request) (traced bool, tagged bool, readable bool) {
+	// custom classifiers can not make the security bodies readable.
+	readable = !isSecurityPath(req.URL.Path)
+
+	operation, ok := r.operationName(req.Method, req.URL.Path)
+	if !ok {
+		return true, true, readable
+	}
+	family, ok := operationFamily(operation)
+	if !ok {
+		return true, true, readable
+	}
+	return !r.disabledFamilies[family], !r.untaggedFamilies[family], readable && family != APIFamilySecurity
+}
+
+func familySet(families []APIFamily) map[APIFamily]bool {
+	set := make(map[APIFamily]bool, len(families))
+	for _, f := range families {
+		set[f] = true
+	}
+	return set
+}
+
+// WithDisabledAPIFamilies disables the span creation for the requests to the
+// given API families, e.g. to leave out the `_cat` calls made by monitoring
+// tools. Those requests are passed through to the parent transport.
+func WithDisabledAPIFamilies(families ...APIFamily) TraceOpt {
+	return func(r *Transport) {
+		r.disabledFamilies = familySet(families)
+	}
+}
+
+// WithUntaggedAPIFamilies records only the minimal tags, as in
+// VerbosityMinimal, for the requests to the given API families while still
+// creating their spans.
+func WithUntaggedAPIFamilies(families ...APIFamily) TraceOpt {
+	return func(r *Transport) {
+		r.untaggedFamilies = familySet(families)
+	}
+}
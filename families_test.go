@@ -0,0 +1,80 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestDisabledAPIFamilies(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+		rw.Write([]byte(`{"hits":{"total":3}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithDisabledAPIFamilies(APIFamilyCat), WithTagTotalHits())
+	for _, path := range []string{"/_cat/indices", "/logs/_search"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if want, have := 2, calls; want != have {
+		t.Errorf("unexpected calls number; want %d, have %d", want, have)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "/logs/_search", spans[0].Tags["http.path"]; want != have {
+		t.Errorf("unexpected path; want %q, have %q", want, have)
+	}
+}
+
+func TestUntaggedAPIFamilies(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"hits":{"total":3}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithUntaggedAPIFamilies(APIFamilyIngest), WithTagTotalHits())
+	for _, path := range []string{"/_ingest/pipeline/logs", "/logs/_search"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "", spans[0].Tags["es.hits.total"]; want != have {
+		t.Errorf("unexpected total hits for the ingest call; want %q, have %q", want, have)
+	}
+
+	if want, have := "3", spans[1].Tags["es.hits.total"]; want != have {
+		t.Errorf("unexpected total hits for the search call; want %q, have %q", want, have)
+	}
+}
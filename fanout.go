@@ -0,0 +1,41 @@
+package zipkines
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+type fanOutKey struct{}
+
+type fanOutGroup struct {
+	id  string
+	seq int64
+}
+
+// ContextWithFanOut returns a context marking the ES calls made with it as
+// part of the same fan-out group, e.g. the parallel queries of a scatter and
+// gather. Every span is tagged with the group ID and the sequence number in
+// which the call was issued so the pattern can be reconstructed in the trace
+// UI. A random group ID is generated if the given one is empty.
+func ContextWithFanOut(ctx context.Context, groupID string) context.Context {
+	if groupID == "" {
+		groupID = fmt.Sprintf("%016x", rand.Uint64())
+	}
+	return context.WithValue(ctx, fanOutKey{}, &fanOutGroup{id: groupID})
+}
+
+// tagFanOut tags the fan-out group and the sequence number of the call if
+// the context belongs to a group.
+func tagFanOut(ctx context.Context, span zipkin.Span) {
+	g, ok := ctx.Value(fanOutKey{}).(*fanOutGroup)
+	if !ok {
+		return
+	}
+
+	span.Tag("es.fanout.group", g.id)
+	span.Tag("es.fanout.seq", fmt.Sprintf("%d", atomic.AddInt64(&g.seq, 1)))
+}
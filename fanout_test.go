@@ -0,0 +1,66 @@
+package zipkines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestFanOutCallsShareGroup(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+	ctx := ContextWithFanOut(context.Background(), "")
+
+	const calls = 5
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", srv.URL+"/my-index/_search", nil)
+			if _, err := transport.RoundTrip(req.WithContext(ctx)); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	spans := reporter.Flush()
+	if want, have := calls, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	group := spans[0].Tags["es.fanout.group"]
+	if group == "" {
+		t.Fatal("expected fan-out group tag")
+	}
+
+	seqs := map[string]bool{}
+	for _, span := range spans {
+		if want, have := group, span.Tags["es.fanout.group"]; want != have {
+			t.Errorf("unexpected group; want %q, have %q", want, have)
+		}
+		seqs[span.Tags["es.fanout.seq"]] = true
+	}
+
+	for _, seq := range []string{"1", "2", "3", "4", "5"} {
+		if !seqs[seq] {
+			t.Errorf("expected sequence number %s", seq)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package zipkines
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithFilter decides per request whether it is traced, requests for which
+// filter returns false get no span and are passed through to the parent
+// transport, e.g. the sniffing calls, the health probes or the requests
+// carrying an internal header.
+func WithFilter(filter func(*http.Request) bool) TraceOpt {
+	return func(r *Transport) {
+		r.filter = filter
+	}
+}
+
+type skipTracingKey struct{}
+
+// SkipTracing returns a context whose ES calls get no span and are passed
+// through to the parent transport, e.g. for a tight polling loop or a warm
+// up query.
+func SkipTracing(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipTracingKey{}, true)
+}
+
+func tracingSkipped(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipTracingKey{}).(bool)
+	return skip
+}
+
+// skipsTracing tells whether a request gets no span, see WithFilter and
+// SkipTracing.
+func (r *Transport) skipsTracing(req *http.Request) bool {
+	return tracingSkipped(req.Context()) || (r.filter != nil && !r.filter(req))
+}
@@ -0,0 +1,87 @@
+package zipkines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestFilter(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	var received int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received++
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithFilter(func(req *http.Request) bool {
+		return req.URL.Path != "/_cluster/health" && req.Header.Get("X-Internal") == ""
+	}))
+
+	for _, tc := range []struct {
+		path     string
+		internal bool
+	}{
+		{"/_cluster/health", false},
+		{"/logs/_search", true},
+		{"/logs/_search", false},
+	} {
+		req, _ := http.NewRequest("GET", srv.URL+tc.path, nil)
+		if tc.internal {
+			req.Header.Set("X-Internal", "1")
+		}
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if want, have := 3, received; want != have {
+		t.Errorf("unexpected requests number; want %d, have %d", want, have)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "/logs/_search", spans[0].Tags["http.path"]; want != have {
+		t.Errorf("unexpected path; want %q, have %q", want, have)
+	}
+}
+
+func TestSkipTracing(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	var received int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received++
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+	for _, ctx := range []context.Context{SkipTracing(context.Background()), context.Background()} {
+		req, _ := http.NewRequest("GET", srv.URL+"/_tasks/abc:1", nil)
+		res, err := transport.RoundTrip(req.WithContext(ctx))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	if want, have := 2, received; want != have {
+		t.Errorf("unexpected requests number; want %d, have %d", want, have)
+	}
+
+	if want, have := 1, len(reporter.Flush()); want != have {
+		t.Errorf("unexpected spans number; want %d, have %d", want, have)
+	}
+}
@@ -0,0 +1,133 @@
+package zipkines
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+)
+
+// fingerprinter hashes the shape of the queries, i.e. the query without its
+// literal values, so equal queries with different values can be grouped.
+type fingerprinter struct {
+	name    string
+	newHash func() hash.Hash
+	key     []byte
+}
+
+func (f *fingerprinter) hash() hash.Hash {
+	if len(f.key) > 0 {
+		return hmac.New(f.newHash, f.key)
+	}
+	return f.newHash()
+}
+
+func (f *fingerprinter) prefix() string {
+	if len(f.key) > 0 {
+		return "hmac-" + f.name + ":"
+	}
+	return f.name + ":"
+}
+
+// sum returns the fingerprint of a JSON or NDJSON body or false if the body
+// is not JSON.
+func (f *fingerprinter) sum(body []byte) (string, bool) {
+	docs, err := NDJSONCodec.Decode(body)
+	if err != nil || len(docs) == 0 {
+		return "", false
+	}
+	return f.sumDocs(docs)
+}
+
+// sumDocs returns the fingerprint of the decoded documents of a body.
+//
+// The normalization is part of the fingerprint contract and must not change
+// as fingerprints are compared across services and releases: the scalar
+// values and the arrays of scalars are replaced by "?" and every document is
+// encoded again with sorted keys. Documents are hashed joined by a new line.
+func (f *fingerprinter) sumDocs(docs []interface{}) (string, bool) {
+	h := f.hash()
+	for i, doc := range docs {
+		shape, err := json.Marshal(queryShape(doc))
+		if err != nil {
+			return "", false
+		}
+
+		if i > 0 {
+			h.Write([]byte("\n"))
+		}
+		h.Write(shape)
+	}
+	return f.prefix() + hex.EncodeToString(h.Sum(nil)[:16]), true
+}
+
+// queryShape replaces the literal values in a decoded document by "?".
+func queryShape(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = queryShape(val)
+		}
+		return v
+	case []interface{}:
+		scalars := true
+		for i, val := range v {
+			v[i] = queryShape(val)
+			if v[i] != "?" {
+				scalars = false
+			}
+		}
+		if scalars {
+			return "?"
+		}
+		return v
+	case nil:
+		return nil
+	}
+	return "?"
+}
+
+// defaultFingerprinter is used by the features relying on the fingerprints
+// when they are not tagged.
+var defaultFingerprinter = &fingerprinter{name: "sha256", newHash: sha256.New}
+
+// queryFingerprinter returns the fingerprinter of the transport.
+func (r *Transport) queryFingerprinter() *fingerprinter {
+	if r.fingerprint != nil {
+		return r.fingerprint
+	}
+	return defaultFingerprinter
+}
+
+// WithQueryFingerprint tags the requests sending a query with a fingerprint
+// of its shape as "es.query.fingerprint", e.g. "sha256:6c4b...". Queries only
+// differing in their values, e.g. the searched terms, share the fingerprint.
+func WithQueryFingerprint() TraceOpt {
+	return func(r *Transport) {
+		if r.fingerprint == nil {
+			r.fingerprint = &fingerprinter{name: "sha256", newHash: sha256.New}
+		}
+	}
+}
+
+// WithQueryFingerprintHash enables the query fingerprint using the given hash
+// function, the name prefixes the fingerprints, e.g. "sha512".
+func WithQueryFingerprintHash(name string, newHash func() hash.Hash) TraceOpt {
+	return func(r *Transport) {
+		WithQueryFingerprint()(r)
+		r.fingerprint.name = name
+		r.fingerprint.newHash = newHash
+	}
+}
+
+// WithQueryFingerprintKey enables the query fingerprint computed as an HMAC
+// with the given key, which acts as a salt so the fingerprints can not be
+// reversed by hashing guessed queries without knowing it. Services sharing
+// the key produce comparable fingerprints.
+func WithQueryFingerprintKey(key []byte) TraceOpt {
+	return func(r *Transport) {
+		WithQueryFingerprint()(r)
+		r.fingerprint.key = append([]byte(nil), key...)
+	}
+}
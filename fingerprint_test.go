@@ -0,0 +1,111 @@
+package zipkines
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestQueryFingerprintIgnoresValues(t *testing.T) {
+	tr := &Transport{}
+	WithQueryFingerprint()(tr)
+
+	a, ok := tr.fingerprint.sum([]byte(`{"query":{"match":{"user":"alice"}},"size":10}`))
+	if !ok {
+		t.Fatal("expected a fingerprint")
+	}
+
+	b, _ := tr.fingerprint.sum([]byte(`{"size":20,"query":{"match":{"user":"bob"}}}`))
+	if want, have := a, b; want != have {
+		t.Errorf("unexpected fingerprint for a query with other values; want %q, have %q", want, have)
+	}
+
+	c, _ := tr.fingerprint.sum([]byte(`{"query":{"term":{"user":"alice"}},"size":10}`))
+	if a == c {
+		t.Errorf("unexpected equal fingerprint for a query with another shape: %q", c)
+	}
+
+	if _, ok := tr.fingerprint.sum([]byte(`not json`)); ok {
+		t.Error("unexpected fingerprint for a non JSON body")
+	}
+}
+
+func TestQueryFingerprintIsStable(t *testing.T) {
+	// fingerprints are compared across services and releases, this value
+	// must never change.
+	tr := &Transport{}
+	WithQueryFingerprint()(tr)
+
+	fingerprint, _ := tr.fingerprint.sum([]byte(`{"query":{"terms":{"tags":["a","b"]}},"size":10}`))
+	if want, have := "sha256:eb3f9e7ef8906453246bb09beeddada1", fingerprint; want != have {
+		t.Errorf("unexpected fingerprint; want %q, have %q", want, have)
+	}
+}
+
+func TestQueryFingerprintHashAndKey(t *testing.T) {
+	query := []byte(`{"query":{"match_all":{}}}`)
+
+	plain := &Transport{}
+	WithQueryFingerprint()(plain)
+	plainSum, _ := plain.fingerprint.sum(query)
+
+	keyed := &Transport{}
+	WithQueryFingerprintKey([]byte("secret"))(keyed)
+	keyedSum, _ := keyed.fingerprint.sum(query)
+	if !strings.HasPrefix(keyedSum, "hmac-sha256:") {
+		t.Errorf("unexpected keyed fingerprint prefix: %q", keyedSum)
+	}
+	if plainSum[len("sha256:"):] == keyedSum[len("hmac-sha256:"):] {
+		t.Error("unexpected keyed fingerprint equal to the plain one")
+	}
+
+	otherKey := &Transport{}
+	WithQueryFingerprintKey([]byte("other"))(otherKey)
+	if otherSum, _ := otherKey.fingerprint.sum(query); otherSum == keyedSum {
+		t.Error("unexpected equal fingerprints for different keys")
+	}
+
+	sha := &Transport{}
+	WithQueryFingerprintHash("sha512", sha512.New)(sha)
+	if shaSum, _ := sha.fingerprint.sum(query); !strings.HasPrefix(shaSum, "sha512:") {
+		t.Errorf("unexpected fingerprint prefix: %q", shaSum)
+	}
+}
+
+func TestQueryFingerprintTag(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithQueryFingerprint())
+	req, _ := http.NewRequest("POST", srv.URL+"/logs/_search", bytes.NewBufferString(`{"query":{"match_all":{}}}`))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if !strings.HasPrefix(spans[0].Tags["es.query.fingerprint"], "sha256:") {
+		t.Errorf("unexpected fingerprint: %q", spans[0].Tags["es.query.fingerprint"])
+	}
+
+	if want, have := "", spans[0].Tags["es.query"]; want != have {
+		t.Errorf("unexpected query; want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,46 @@
+package zipkines
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// uncompressedBody returns the body of a response to be parsed, undoing its
+// content encoding which is still there when the client asked for a
+// compressed response itself, as the parent transport only decompresses the
+// responses it asked to be compressed. False is returned if the encoding is
+// not supported or the body can not be decompressed within the limit of the
+// bodies of unknown length.
+func (r *Transport) uncompressedBody(res *http.Response, body []byte) ([]byte, bool) {
+	var zr io.Reader
+	var err error
+	switch strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return body, true
+	case "gzip", "x-gzip":
+		zr, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		zr, err = zlib.NewReader(bytes.NewReader(body))
+	default:
+		return nil, false
+	}
+	if err != nil {
+		return nil, false
+	}
+
+	limit := r.bodyReadLimit(-1)
+	if limit > 0 {
+		zr = io.LimitReader(zr, limit+1)
+	}
+
+	uncompressed, err := ioutil.ReadAll(zr)
+	if err != nil || (limit > 0 && int64(len(uncompressed)) > limit) {
+		return nil, false
+	}
+	return uncompressed, true
+}
@@ -0,0 +1,67 @@
+package zipkines
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func gzipped(t *testing.T, body string) []byte {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write([]byte(body)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestCompressedResponses(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	success := gzipped(t, `{"_shards":{"total":5},"hits":{"total":42}}`)
+	failure := gzipped(t, `{"type":"index_not_found_exception"}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Encoding", "gzip")
+		if req.URL.Path == "/missing/_search" {
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write(failure)
+			return
+		}
+		rw.Write(success)
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagTotalHits(), WithTagTotalShards(), WithTagErrorType())
+	for _, path := range []string{"/logs/_search", "/missing/_search"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		// asking for compression disables the decompression of the parent
+		req.Header.Set("Accept-Encoding", "gzip")
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "42", spans[0].Tags["es.hits.total"]; want != have {
+		t.Errorf("unexpected hits total; want %q, have %q", want, have)
+	}
+
+	if want, have := "5", spans[0].Tags["es.shards.total"]; want != have {
+		t.Errorf("unexpected shards total; want %q, have %q", want, have)
+	}
+
+	if want, have := "index_not_found_exception", spans[1].Tags["error"]; want != have {
+		t.Errorf("unexpected error; want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,91 @@
+package zipkines
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+const unknownClusterStatus = "unknown"
+
+type clusterHealthResponse struct {
+	Status           string `json:"status"`
+	NumberOfNodes    *int   `json:"number_of_nodes"`
+	UnassignedShards *int   `json:"unassigned_shards"`
+}
+
+// tagClusterHealth tags the status, the number of nodes and the unassigned
+// shards of a cluster health response. A red status tags the span as an
+// error so the degradation stands out.
+func tagClusterHealth(span zipkin.Span, body []byte) error {
+	health := clusterHealthResponse{}
+	if err := json.Unmarshal(body, &health); err != nil {
+		return err
+	}
+
+	if health.Status != "" {
+		span.Tag("es.cluster.status", health.Status)
+	}
+	if health.NumberOfNodes != nil {
+		span.Tag("es.cluster.number_of_nodes", fmt.Sprintf("%d", *health.NumberOfNodes))
+	}
+	if health.UnassignedShards != nil {
+		span.Tag("es.cluster.unassigned_shards", fmt.Sprintf("%d", *health.UnassignedShards))
+	}
+	if health.Status == "red" {
+		zipkin.TagError.Set(span, "cluster status red")
+	}
+	return nil
+}
+
+// StartClusterHealthPoller polls the `/_cluster/health` endpoint of the
+// cluster at the given base URL, e.g. "http://es-01:9200", once every
+// interval until the context is cancelled. The latest status (green, yellow
+// or red) is tagged onto every span as "es.cluster.polled_status" so latency
+// anomalies can be correlated with the cluster degradation. The status is
+// "unknown" while the poll fails, including when ES answers with a non
+// successful status code. The first poll happens before returning. Polls go
+// straight to the parent transport hence they are not traced, the
+// credentials can be set through WithPollRequestDecorator. It returns
+// ErrInvalidPollInterval if the interval is not positive.
+func (r *Transport) StartClusterHealthPoller(ctx context.Context, url string, interval time.Duration, opts ...PollOpt) error {
+	p := newPoller(opts)
+	return startPolling(ctx, interval, func() {
+		r.pollClusterHealth(ctx, p, url)
+	})
+}
+
+func (r *Transport) pollClusterHealth(ctx context.Context, p *poller, url string) {
+	status, err := r.fetchClusterHealth(ctx, p, url)
+	if err != nil {
+		r.logger.Printf("failed to poll the cluster health: %v", err)
+		status = unknownClusterStatus
+	}
+	r.clusterStatus.Store(status)
+}
+
+func (r *Transport) fetchClusterHealth(ctx context.Context, p *poller, url string) (string, error) {
+	body, err := p.fetch(ctx, r.parent, url+"/_cluster/health", 1<<20)
+	if err != nil {
+		return "", err
+	}
+
+	health := clusterHealthResponse{}
+	if err := json.Unmarshal(body, &health); err != nil {
+		return "", err
+	}
+
+	if health.Status == "" {
+		return unknownClusterStatus, nil
+	}
+	return health.Status, nil
+}
+
+// polledClusterStatus returns the latest polled cluster status, if any.
+func (r *Transport) polledClusterStatus() string {
+	status, _ := r.clusterStatus.Load().(string)
+	return status
+}
@@ -0,0 +1,137 @@
+package zipkines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestClusterHealthPoller(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/_cluster/health" {
+			if user, pass, _ := req.BasicAuth(); user != "monitoring" || pass != "secret" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				rw.Write([]byte(`{"status":401}`))
+				return
+			}
+			rw.Write([]byte(`{"cluster_name":"es","status":"yellow"}`))
+			return
+		}
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = transport.StartClusterHealthPoller(ctx, srv.URL, time.Hour, WithPollRequestDecorator(func(req *http.Request) {
+		req.SetBasicAuth("monitoring", "secret")
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", srv.URL+"/logs/_search", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "yellow", spans[0].Tags["es.cluster.polled_status"]; want != have {
+		t.Errorf("unexpected polled status; want %q, have %q", want, have)
+	}
+}
+
+func TestClusterHealthPollerFailure(t *testing.T) {
+	tracer, _ := zipkin.NewTracer(recorder.NewReporter())
+	transport := NewTransport(tracer, WithLogger(discardLogger))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusUnauthorized)
+		rw.Write([]byte(`{"status":"red"}`))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, url := range []string{"http://127.0.0.1:1", srv.URL} {
+		transport.clusterStatus.Store("")
+		if err := transport.StartClusterHealthPoller(ctx, url, time.Hour); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if want, have := "unknown", transport.polledClusterStatus(); want != have {
+			t.Errorf("unexpected polled status for %s; want %q, have %q", url, want, have)
+		}
+	}
+
+	if want, have := ErrInvalidPollInterval, transport.StartClusterHealthPoller(ctx, srv.URL, 0); want != have {
+		t.Errorf("unexpected error for a zero interval; want %v, have %v", want, have)
+	}
+}
+
+func TestClusterHealthIsTagged(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/_cluster/health/orders" {
+			rw.Write([]byte(`{"status":"red","number_of_nodes":3,"unassigned_shards":2}`))
+			return
+		}
+		rw.Write([]byte(`{"status":"green","number_of_nodes":3,"unassigned_shards":0}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+	for _, path := range []string{"/_cluster/health", "/_cluster/health/orders"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expectedTags := []map[string]string{
+		{
+			"es.cluster.status":            "green",
+			"es.cluster.number_of_nodes":   "3",
+			"es.cluster.unassigned_shards": "0",
+			"error":                        "",
+		},
+		{
+			"es.cluster.status":            "red",
+			"es.cluster.unassigned_shards": "2",
+			"error":                        "cluster status red",
+		},
+	}
+	for i, tags := range expectedTags {
+		for key, val := range tags {
+			if want, have := val, spans[i].Tags[key]; want != have {
+				t.Errorf("unexpected %q tag for span %d; want %q, have %q", key, i, want, have)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package zipkines
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// defaultLatencyBounds are the upper bounds of the latency buckets when none
+// are given.
+var defaultLatencyBounds = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+type histogramKey struct {
+	operation string
+	index     string
+}
+
+// latencyHistogram buckets the call durations per operation and index for a
+// window of time. Like the index rollup it records every call regardless of
+// the sampling decision.
+type latencyHistogram struct {
+	*window
+	bounds []time.Duration
+}
+
+func newLatencyHistogram(interval time.Duration, bounds []time.Duration) *latencyHistogram {
+	if len(bounds) == 0 {
+		bounds = defaultLatencyBounds
+	}
+	bounds = append([]time.Duration(nil), bounds...)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	return &latencyHistogram{
+		// the counts hold one count per bound plus the overflow bucket.
+		window: newWindow(interval, func() interface{} {
+			return map[histogramKey][]int{}
+		}),
+		bounds: bounds,
+	}
+}
+
+// record adds a call to the current window. If the window is over it is
+// closed and returned so the caller can emit it outside of the lock.
+func (h *latencyHistogram) record(now time.Time, key histogramKey, d time.Duration) (time.Time, map[histogramKey][]int) {
+	if key.index == "" {
+		key.index = rollupAllIndices
+	}
+
+	start, closed := h.window.record(now, func(state interface{}) {
+		all := state.(map[histogramKey][]int)
+		counts, ok := all[key]
+		if !ok {
+			counts = make([]int, len(h.bounds)+1)
+			all[key] = counts
+		}
+		counts[sort.Search(len(h.bounds), func(i int) bool { return d <= h.bounds[i] })]++
+	})
+	if closed == nil {
+		return time.Time{}, nil
+	}
+	return start, closed.(map[histogramKey][]int)
+}
+
+// emitHistogram reports one local span per operation and index covering the
+// closed window, tagged with the count of calls per bucket as
+// "es.latency.le_<bound>ms" plus "es.latency.gt_<last bound>ms". Empty
+// buckets are not tagged.
+func (r *Transport) emitHistogram(start, end time.Time, counts map[histogramKey][]int) {
+	bounds := r.histogram.bounds
+	for key, c := range counts {
+		r.emitWindowSpan("es/latency_histogram", start, end, func(span zipkin.Span) {
+			TagESOperation.Set(span, key.operation)
+			TagESIndex.Set(span, key.index)
+			for i, n := range c {
+				if n == 0 {
+					continue
+				}
+				if i < len(bounds) {
+					span.Tag(fmt.Sprintf("es.latency.le_%dms", bounds[i].Milliseconds()), fmt.Sprintf("%d", n))
+				} else {
+					span.Tag(fmt.Sprintf("es.latency.gt_%dms", bounds[len(bounds)-1].Milliseconds()), fmt.Sprintf("%d", n))
+				}
+			}
+		})
+	}
+}
+
+// recordLatency adds a call to the latency histogram, if enabled.
+func (r *Transport) recordLatency(req *http.Request, now time.Time, d time.Duration) {
+	if r.histogram == nil {
+		return
+	}
+
+	operation, ok := r.operationName(req.Method, req.URL.Path)
+	if !ok {
+		operation = req.Method
+	}
+
+	key := histogramKey{operation: operation, index: indexFromPath(req.URL.Path)}
+	if start, counts := r.histogram.record(now, key, d); counts != nil {
+		r.emitHistogram(start, now, counts)
+	}
+}
+
+// WithLatencyHistogram enables bucketing the call durations per operation and
+// index, emitted as local spans named "es/latency_histogram" once every
+// interval, to get rough latency distributions under aggressive sampling.
+// The bounds are the upper bounds of the buckets, a default set ranging from
+// 5ms to 5s is used if none are given. As for the index rollup, windows are
+// closed by the first call made after the interval is over and the spans are
+// dated by the clock set through WithClock.
+func WithLatencyHistogram(interval time.Duration, bounds ...time.Duration) TraceOpt {
+	return func(r *Transport) {
+		r.histogram = newLatencyHistogram(interval, bounds)
+	}
+}
@@ -0,0 +1,62 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.NeverSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	// every call to the clock moves it forward, hence each call takes the
+	// given latency.
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	var latency time.Duration
+	clock := func() time.Time {
+		now = now.Add(latency)
+		return now
+	}
+	transport := NewTransport(
+		tracer,
+		WithLatencyHistogram(time.Minute, 100*time.Millisecond, 10*time.Millisecond),
+		WithClock(clock),
+	)
+
+	for _, l := range []time.Duration{5 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond, time.Minute} {
+		latency = l
+		req, _ := http.NewRequest("POST", srv.URL+"/logs/_search", nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expectedTags := map[string]string{
+		"es.operation":        "search",
+		"es.index":            "logs",
+		"es.latency.le_10ms":  "1",
+		"es.latency.le_100ms": "2",
+		"es.latency.gt_100ms": "1",
+	}
+	for key, val := range expectedTags {
+		if want, have := val, spans[0].Tags[key]; want != have {
+			t.Errorf("unexpected %q tag; want %q, have %q", key, want, have)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package zipkines
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// connectionTrace returns a client trace annotating the span with the
+// connection milestones of a request and a function to call before the span
+// is finished. With HTTP/2 only the request opening a connection sees its
+// milestones as the following ones are multiplexed over it.
+//
+// The dials started for a request can complete after it got a pooled
+// connection and its span was finished, hence the milestones reported once
+// the returned function is called are dropped.
+func connectionTrace(span zipkin.Span) (*httptrace.ClientTrace, func()) {
+	var (
+		mu   sync.Mutex
+		done bool
+	)
+	annotate := func(value string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !done {
+			span.Annotate(time.Now(), value)
+		}
+	}
+	stop := func() {
+		mu.Lock()
+		done = true
+		mu.Unlock()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			annotate("dns.start")
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			annotate("dns.done")
+		},
+		ConnectStart: func(string, string) {
+			annotate("connect.start")
+		},
+		ConnectDone: func(string, string, error) {
+			annotate("connect.done")
+		},
+		TLSHandshakeStart: func() {
+			annotate("tls.start")
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			annotate("tls.done")
+		},
+		GotFirstResponseByte: func() {
+			annotate("first_byte")
+		},
+	}, stop
+}
+
+// WithConnectionAnnotations annotates the spans with the connection
+// milestones of the requests, i.e. "dns.start", "dns.done", "connect.start",
+// "connect.done", "tls.start", "tls.done" and "first_byte", so the slow calls
+// can be attributed to the connection setup or to the server. Requests
+// reusing a pooled connection only get the "first_byte" annotation.
+func WithConnectionAnnotations() TraceOpt {
+	return func(r *Transport) {
+		r.connectionAnnotations = true
+	}
+}
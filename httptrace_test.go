@@ -0,0 +1,83 @@
+package zipkines
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestConnectionAnnotations(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, RoundTripper(srv.Client().Transport), WithConnectionAnnotations())
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", srv.URL+"/logs/_search", nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// drains the body so the connection is given back to the pool.
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expected := [][]string{
+		{"connect.start", "connect.done", "tls.start", "tls.done", "first_byte"},
+		// the second request reuses the connection
+		{"first_byte"},
+	}
+	for i, values := range expected {
+		annotations := map[string]bool{}
+		for _, a := range spans[i].Annotations {
+			annotations[a.Value] = true
+		}
+
+		if want, have := len(values), len(spans[i].Annotations); want != have {
+			t.Errorf("unexpected annotations number for span %d; want %d, have %d", i, want, have)
+		}
+
+		for _, value := range values {
+			if !annotations[value] {
+				t.Errorf("expected %q annotation for span %d", value, i)
+			}
+		}
+	}
+}
+
+func TestConnectionAnnotationsAreDroppedOnceStopped(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	span := tracer.StartSpan("es/_search")
+	trace, stop := connectionTrace(span)
+	trace.ConnectStart("tcp", "127.0.0.1:9200")
+	stop()
+	// a dial completing after the request got a pooled connection.
+	trace.ConnectDone("tcp", "127.0.0.1:9200", nil)
+	span.Finish()
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans[0].Annotations); want != have {
+		t.Fatalf("unexpected annotations number; want %d, have %d", want, have)
+	}
+
+	if want, have := "connect.start", spans[0].Annotations[0].Value; want != have {
+		t.Errorf("unexpected annotation; want %q, have %q", want, have)
+	}
+}
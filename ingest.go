@@ -0,0 +1,23 @@
+package zipkines
+
+import (
+	"strings"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// tagIngestManagement tags the name of the enrich policy
+// (`/_enrich/policy/*`) or the ingest pipeline (`/_ingest/pipeline/*`)
+// addressed by their CRUD calls, if any.
+func tagIngestManagement(span zipkin.Span, m EndpointMatch) {
+	if m.ID == "" {
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(m.Operation, "enrich."):
+		span.Tag("es.enrich.policy", m.ID)
+	case strings.HasPrefix(m.Operation, "ingest."):
+		span.Tag("es.ingest.pipeline", m.ID)
+	}
+}
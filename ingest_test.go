@@ -0,0 +1,60 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestIngestManagementNaming(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+
+	testCases := []struct {
+		method       string
+		path         string
+		expectedName string
+		expectedTag  string
+		expectedVal  string
+	}{
+		{"PUT", "/_enrich/policy/users", "es/enrich.put_policy", "es.enrich.policy", "users"},
+		{"POST", "/_enrich/policy/users/_execute", "es/enrich.execute_policy", "es.enrich.policy", "users"},
+		{"DELETE", "/_enrich/policy/users", "es/enrich.delete_policy", "es.enrich.policy", "users"},
+		{"GET", "/_ingest/pipeline", "es/ingest.get_pipeline", "es.ingest.pipeline", ""},
+		{"PUT", "/_ingest/pipeline/geoip", "es/ingest.put_pipeline", "es.ingest.pipeline", "geoip"},
+		{"POST", "/_ingest/pipeline/geoip/_simulate", "es/ingest.simulate", "es.ingest.pipeline", "geoip"},
+	}
+
+	for _, tc := range testCases {
+		req, _ := http.NewRequest(tc.method, srv.URL+tc.path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		spans := reporter.Flush()
+		if want, have := 1, len(spans); want != have {
+			t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+		}
+
+		if want, have := tc.expectedName, spans[0].Name; want != have {
+			t.Errorf("unexpected span name; want %q, have %q", want, have)
+		}
+
+		if want, have := tc.expectedVal, spans[0].Tags[tc.expectedTag]; want != have {
+			t.Errorf("unexpected %q tag; want %q, have %q", tc.expectedTag, want, have)
+		}
+	}
+}
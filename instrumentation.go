@@ -0,0 +1,188 @@
+package zipkines
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// Instrumentation traces the calls made by the official go-elasticsearch v8
+// client through its instrumentation hooks, i.e. it implements the
+// `elastictransport.Instrumentation` interface:
+//
+//	cfg := elasticsearch.Config{
+//		Instrumentation: zipkines.NewInstrumentation(tracer),
+//	}
+//
+// The client names the endpoints itself, hence the spans are named after
+// them, e.g. "es/search", and the body of the responses is not parsed. It
+// must not be combined with a traced transport, otherwise traces will be
+// duplicated.
+type Instrumentation struct {
+	t *Transport
+}
+
+// NewInstrumentation returns an Instrumentation for the official client. The
+// tagging options apply as for NewTransport, although only the ones about the
+// request are supported.
+func NewInstrumentation(tracer *zipkin.Tracer, opts ...TraceOpt) *Instrumentation {
+	return &Instrumentation{t: NewTransport(tracer, opts...)}
+}
+
+// instrumentedCallKey keys the endpoint call started by Start, the hooks
+// must not touch the span of the caller when the call is not traced.
+type instrumentedCallKey struct{}
+
+type instrumentedCall struct {
+	span zipkin.Span
+	// dropped is set when the request is filtered out, the span is then
+	// never finished hence not reported.
+	dropped bool
+}
+
+// span returns the span of the endpoint call of ctx, nil if not traced.
+func (i *Instrumentation) span(ctx context.Context) zipkin.Span {
+	call, _ := ctx.Value(instrumentedCallKey{}).(*instrumentedCall)
+	if call == nil || call.dropped {
+		return nil
+	}
+	return call.span
+}
+
+// Start starts the span of an endpoint call as the transport does, as a
+// child of the operation or the span of the context, unless the tracing is
+// skipped, see SkipTracing.
+func (i *Instrumentation) Start(ctx context.Context, name string) context.Context {
+	if tracingSkipped(ctx) {
+		return ctx
+	}
+
+	parent, hasParent := spanParent(ctx)
+	if hasParent && !i.t.admitSpan(parent) {
+		return ctx
+	}
+
+	span := i.t.startClientSpan(ctx, i.t.spanName("es/"+name), parent, hasParent, time.Now())
+	TagESOperation.Set(span, name)
+	ctx = context.WithValue(ctx, instrumentedCallKey{}, &instrumentedCall{span: span})
+	return zipkin.NewContext(ctx, span)
+}
+
+// Close finishes the span of an endpoint call.
+func (i *Instrumentation) Close(ctx context.Context) {
+	if span := i.span(ctx); span != nil {
+		span.Finish()
+	}
+}
+
+// RecordError tags the error of an endpoint call.
+func (i *Instrumentation) RecordError(ctx context.Context, err error) {
+	if span := i.span(ctx); span != nil && err != nil {
+		zipkin.TagError.Set(span, err.Error())
+	}
+}
+
+// RecordPathPart tags the path variables of an endpoint call as
+// "es.path.<part>", e.g. "es.path.index". The document IDs are subject to
+// the document ID redaction.
+func (i *Instrumentation) RecordPathPart(ctx context.Context, pathPart, value string) {
+	span := i.span(ctx)
+	if span == nil {
+		return
+	}
+
+	if pathPart == "id" || pathPart == "document_id" {
+		value = i.t.opts.DocIDPolicy.redact(value)
+	}
+	span.Tag("es.path."+pathPart, value)
+	if pathPart == "index" {
+		TagESIndex.Set(span, value)
+	}
+}
+
+// RecordRequestBody tags the query, if query tagging is enabled, and returns
+// the body to be sent instead of the given one or nil to keep it. No more
+// than the maximum chunked body read is buffered and bigger bodies are not
+// tagged.
+func (i *Instrumentation) RecordRequestBody(ctx context.Context, endpoint string, query io.Reader) io.ReadCloser {
+	span := i.span(ctx)
+	if span == nil || query == nil || !i.t.opts.TagQuery || !(i.t.opts.TagUnsampled || isSampled(span)) || isSecurityEndpoint(endpoint) {
+		return nil
+	}
+
+	orig, ok := query.(io.ReadCloser)
+	if !ok {
+		orig = ioutil.NopCloser(query)
+	}
+
+	var limited io.Reader = orig
+	limit := i.t.bodyReadLimit(-1)
+	if limit > 0 {
+		limited = io.LimitReader(orig, limit+1)
+	}
+
+	body, err := ioutil.ReadAll(limited)
+	if err != nil {
+		i.t.logger.Printf("failed to read the request body to tag the query: %v", err)
+	} else if limit > 0 && int64(len(body)) > limit {
+		i.t.skipBodyRead()
+	} else if len(body) > 0 {
+		if val, ok := i.t.queryTagValue(i.t.opts, "", endpoint, body); ok {
+			TagESQuery.Set(span, val)
+		}
+	}
+	return replayBody(body, orig)
+}
+
+// BeforeRequest tags the request method and path and injects the
+// propagation headers. The span of a request filtered out, see WithFilter,
+// is dropped.
+func (i *Instrumentation) BeforeRequest(req *http.Request, endpoint string) {
+	call, _ := req.Context().Value(instrumentedCallKey{}).(*instrumentedCall)
+	if call == nil || call.dropped {
+		return
+	}
+
+	if i.t.filter != nil && !i.t.filter(req) {
+		call.dropped = true
+		return
+	}
+
+	span := call.span
+
+	zipkin.TagHTTPMethod.Set(span, req.Method)
+	zipkin.TagHTTPPath.Set(span, i.t.opts.DocIDPolicy.redactPath(req.URL.Path))
+	// the client sends this very request, the headers are injected in place.
+	req.Header = i.t.injectHeaders(req, span.Context()).Header
+}
+
+// AfterRequest sets the remote endpoint once the client picked the node
+// the request is sent to.
+func (i *Instrumentation) AfterRequest(req *http.Request, system, endpoint string) {
+	span := i.span(req.Context())
+	if span == nil {
+		return
+	}
+
+	span.SetRemoteEndpoint(i.t.remoteEndpoint(req, i.t.opts.TagHost))
+}
+
+// AfterResponse tags the status code of the response and, if it is not
+// successful, the error according to the error policy. Responses without
+// their request are errors as the policy can not tell.
+func (i *Instrumentation) AfterResponse(ctx context.Context, res *http.Response) {
+	span := i.span(ctx)
+	if span == nil || res == nil {
+		return
+	}
+
+	zipkin.TagHTTPStatusCode.Set(span, fmt.Sprintf("%d", res.StatusCode))
+	if (res.StatusCode < 200 || res.StatusCode > 299) && (res.Request == nil || i.t.errorPolicy(res.Request, res.StatusCode)) {
+		zipkin.TagError.Set(span, fmt.Sprintf("%d", res.StatusCode))
+	}
+}
@@ -0,0 +1,193 @@
+package zipkines
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/elastic/elastic-transport-go/v8/elastictransport"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+// the hooks are checked against the interface of the official client to
+// catch any signature drift.
+var _ elastictransport.Instrumentation = (*Instrumentation)(nil)
+
+func TestInstrumentation(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var instrument elastictransport.Instrumentation = NewInstrumentation(tracer, WithTagQuery())
+
+	// the calls made by the official client for a search
+	ctx := instrument.Start(context.Background(), "search")
+	instrument.RecordPathPart(ctx, "index", "logs")
+	body := instrument.RecordRequestBody(ctx, "search", strings.NewReader(`{"query":{"match_all":{}}}`))
+	req, _ := http.NewRequest("POST", "http://10.0.0.1:9200/logs/_search", body)
+	req = req.WithContext(ctx)
+	instrument.BeforeRequest(req, "search")
+	instrument.AfterRequest(req, "elasticsearch", "search")
+	instrument.AfterResponse(ctx, &http.Response{StatusCode: 200})
+	instrument.Close(ctx)
+
+	sent, _ := ioutil.ReadAll(req.Body)
+	if want, have := `{"query":{"match_all":{}}}`, string(sent); want != have {
+		t.Errorf("unexpected body sent; want %q, have %q", want, have)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "es/search", spans[0].Name; want != have {
+		t.Errorf("unexpected span name; want %q, have %q", want, have)
+	}
+
+	expectedTags := map[string]string{
+		"es.index":         "logs",
+		"es.query":         `{"query":{"match_all":{}}}`,
+		"http.method":      "POST",
+		"http.path":        "/logs/_search",
+		"http.status_code": "200",
+	}
+	for key, val := range expectedTags {
+		if want, have := val, spans[0].Tags[key]; want != have {
+			t.Errorf("unexpected %q tag; want %q, have %q", key, want, have)
+		}
+	}
+
+	if want, have := "10.0.0.1", spans[0].RemoteEndpoint.IPv4.String(); want != have {
+		t.Errorf("unexpected remote IPv4; want %q, have %q", want, have)
+	}
+}
+
+func TestInstrumentationRecordsErrors(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	instrument := NewInstrumentation(tracer)
+	ctx := instrument.Start(context.Background(), "get")
+	if body := instrument.RecordRequestBody(ctx, "get", strings.NewReader(`{}`)); body != nil {
+		t.Error("unexpected body replacement without query tagging")
+	}
+	instrument.RecordError(ctx, io.ErrUnexpectedEOF)
+	instrument.Close(ctx)
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := io.ErrUnexpectedEOF.Error(), spans[0].Tags["error"]; want != have {
+		t.Errorf("unexpected error; want %q, have %q", want, have)
+	}
+}
+
+func TestInstrumentationHonoursTheErrorPolicy(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	instrument := NewInstrumentation(tracer)
+	testCases := []struct {
+		endpoint, method, path string
+		expectedError          string
+	}{
+		{"get", "GET", "/logs/_doc/1", ""},
+		{"exists", "HEAD", "/logs/_doc/1", ""},
+		{"search", "GET", "/missing/_search", "404"},
+	}
+
+	for _, tc := range testCases {
+		ctx := instrument.Start(context.Background(), tc.endpoint)
+		req, _ := http.NewRequest(tc.method, "http://10.0.0.1:9200"+tc.path, nil)
+		req = req.WithContext(ctx)
+		instrument.BeforeRequest(req, tc.endpoint)
+		instrument.AfterResponse(ctx, &http.Response{StatusCode: 404, Request: req})
+		instrument.Close(ctx)
+
+		spans := reporter.Flush()
+		if want, have := 1, len(spans); want != have {
+			t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+		}
+
+		if want, have := tc.expectedError, spans[0].Tags["error"]; want != have {
+			t.Errorf("unexpected error for %s %s; want %q, have %q", tc.method, tc.path, want, have)
+		}
+	}
+}
+
+func TestInstrumentationStartsSpansAsTheTransport(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	instrument := NewInstrumentation(
+		tracer,
+		WithDefaultTags(map[string]string{"team": "search"}),
+		WithB3Propagation(B3Single),
+		WithFilter(func(req *http.Request) bool {
+			return req.URL.Path != "/_nodes/http"
+		}),
+	)
+
+	operation, ctx := tracer.StartSpanFromContext(context.Background(), "checkout")
+	ctx = ContextWithTags(ContextWithOperation(ctx, operation.Context()), map[string]string{"tenant": "acme"})
+
+	callCtx := instrument.Start(ctx, "search")
+	req, _ := http.NewRequest("POST", "http://10.0.0.1:9200/logs/_search", nil)
+	req = req.WithContext(callCtx)
+	instrument.BeforeRequest(req, "search")
+	instrument.Close(callCtx)
+
+	// the filtered out requests and the skipped calls get no span.
+	callCtx = instrument.Start(ctx, "nodes.info")
+	nodesReq, _ := http.NewRequest("GET", "http://10.0.0.1:9200/_nodes/http", nil)
+	instrument.BeforeRequest(nodesReq.WithContext(callCtx), "nodes.info")
+	instrument.Close(callCtx)
+
+	callCtx = instrument.Start(SkipTracing(ctx), "search")
+	instrument.RecordError(callCtx, io.ErrUnexpectedEOF)
+	instrument.Close(callCtx)
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := operation.Context().ID, *spans[0].ParentID; want != have {
+		t.Errorf("unexpected parent; want %s, have %s", want, have)
+	}
+
+	if want, have := "search", spans[0].Tags["team"]; want != have {
+		t.Errorf("unexpected default tag; want %q, have %q", want, have)
+	}
+
+	if want, have := "acme", spans[0].Tags["tenant"]; want != have {
+		t.Errorf("unexpected context tag; want %q, have %q", want, have)
+	}
+
+	if want, have := spans[0].TraceID.String()+"-"+spans[0].ID.String()+"-1-"+spans[0].ParentID.String(), req.Header.Get("b3"); want != have {
+		t.Errorf("unexpected b3 header; want %q, have %q", want, have)
+	}
+
+	operation.Finish()
+	if want, have := "", reporter.Flush()[0].Tags["error"]; want != have {
+		t.Errorf("unexpected error on the caller span; want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,57 @@
+package zipkines
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+type printfLogger interface {
+	Printf(format string, v ...interface{})
+}
+
+// spanLogger prefixes the log lines with the trace and span IDs of a span.
+// The IDs are only formatted when something is logged.
+type spanLogger struct {
+	logger *log.Logger
+	span   zipkin.Span
+}
+
+func (l spanLogger) Printf(format string, v ...interface{}) {
+	l.logger.Print(spanLogPrefix(l.span) + fmt.Sprintf(format, v...))
+}
+
+func spanLogPrefix(span zipkin.Span) string {
+	sc := span.Context()
+	return fmt.Sprintf("[trace_id=%s span_id=%s] ", sc.TraceID, sc.ID)
+}
+
+// requestLogger returns the logger to be used while tracing a request with
+// the given span.
+func (r *Transport) requestLogger(span zipkin.Span) printfLogger {
+	if r.spanScopedLogging {
+		return spanLogger{logger: r.logger, span: span}
+	}
+	return r.logger
+}
+
+// NewSpanLogger returns a logger writing to l whose lines are prefixed with
+// the trace and span IDs of the span in the context, so application logs can
+// be correlated with the traces. It returns l if there is no span.
+func NewSpanLogger(ctx context.Context, l *log.Logger) *log.Logger {
+	span := zipkin.SpanFromContext(ctx)
+	if span == nil {
+		return l
+	}
+	return log.New(l.Writer(), l.Prefix()+spanLogPrefix(span), l.Flags())
+}
+
+// WithSpanScopedLogging prefixes the lines logged by the transport while
+// tracing a request with the trace and span IDs of its span.
+func WithSpanScopedLogging() TraceOpt {
+	return func(r *Transport) {
+		r.spanScopedLogging = true
+	}
+}
@@ -0,0 +1,68 @@
+package zipkines
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestSpanScopedLogging(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`not json`))
+	}))
+	defer srv.Close()
+
+	out := &bytes.Buffer{}
+	transport := NewTransport(
+		tracer,
+		WithLogger(log.New(out, "", 0)),
+		WithSpanScopedLogging(),
+		WithTagIndexStats(),
+	)
+	req, _ := http.NewRequest("GET", srv.URL+"/_stats", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	prefix := "[trace_id=" + spans[0].TraceID.String() + " span_id=" + spans[0].ID.String() + "] "
+	if !strings.HasPrefix(out.String(), prefix) {
+		t.Errorf("unexpected log line; want prefix %q, have %q", prefix, out.String())
+	}
+}
+
+func TestNewSpanLogger(t *testing.T) {
+	tracer, _ := zipkin.NewTracer(recorder.NewReporter())
+	span := tracer.StartSpan("test")
+
+	out := &bytes.Buffer{}
+	l := log.New(out, "app: ", 0)
+
+	if NewSpanLogger(context.Background(), l) != l {
+		t.Errorf("expected the same logger without span in context")
+	}
+
+	NewSpanLogger(zipkin.NewContext(context.Background(), span), l).Print("hello")
+	expected := "app: " + spanLogPrefix(span) + "hello\n"
+	if want, have := expected, out.String(); want != have {
+		t.Errorf("unexpected log line; want %q, have %q", want, have)
+	}
+}
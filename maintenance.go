@@ -0,0 +1,19 @@
+package zipkines
+
+import (
+	"net/http"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// tagMaintenance tags the index maintenance calls, e.g.
+// `POST /{index}/_forcemerge`, which are named regardless of the method.
+func tagMaintenance(span zipkin.Span, req *http.Request, m EndpointMatch) {
+	if m.Operation != "indices.forcemerge" {
+		return
+	}
+
+	if val := req.URL.Query().Get("max_num_segments"); val != "" {
+		span.Tag("es.forcemerge.max_num_segments", val)
+	}
+}
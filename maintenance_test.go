@@ -0,0 +1,61 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestMaintenanceEndpoints(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+
+	testCases := []struct {
+		method           string
+		path             string
+		expectedName     string
+		expectedIndex    string
+		expectedSegments string
+	}{
+		{"POST", "/logs-*/_forcemerge?max_num_segments=1", "es/_forcemerge", "logs-*", "1"},
+		{"POST", "/_refresh", "es/_refresh", "", ""},
+		{"PUT", "/logs/_flush", "es/_flush", "logs", ""},
+	}
+
+	for _, tc := range testCases {
+		req, _ := http.NewRequest(tc.method, srv.URL+tc.path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		spans := reporter.Flush()
+		if want, have := 1, len(spans); want != have {
+			t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+		}
+
+		if want, have := tc.expectedName, spans[0].Name; want != have {
+			t.Errorf("unexpected span name; want %q, have %q", want, have)
+		}
+
+		if want, have := tc.expectedIndex, spans[0].Tags["es.index"]; want != have {
+			t.Errorf("unexpected index; want %q, have %q", want, have)
+		}
+
+		if want, have := tc.expectedSegments, spans[0].Tags["es.forcemerge.max_num_segments"]; want != have {
+			t.Errorf("unexpected max_num_segments; want %q, have %q", want, have)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package zipkines
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type responseMetaKey struct{}
+
+// ResponseMeta holds the values parsed by the transport from a successful
+// ES response so applications needing them don't parse the body again.
+type ResponseMeta struct {
+	// Took is the time in milliseconds ES took to process the request.
+	Took int
+	// HitsTotal is the total number of hits matching a query.
+	HitsTotal int
+	// HitsRelation tells whether HitsTotal is accurate, "eq", or a lower
+	// bound, "gte". It is empty for the responses of ES 6 and earlier.
+	HitsRelation string
+	// ShardsTotal is the total number of shards queried.
+	ShardsTotal int
+}
+
+type metaResponse struct {
+	Took int `json:"took"`
+	successHitsNShardsResponse
+}
+
+// fill parses the values from a response body, leaving the ones which are
+// missing or can not be parsed untouched.
+func (m *ResponseMeta) fill(body []byte) error {
+	res := metaResponse{Took: m.Took}
+	res.Hits.Total = hitsTotal{Value: m.HitsTotal, Relation: m.HitsRelation}
+	res.Shards.Total = m.ShardsTotal
+
+	// json.Unmarshal leaves the fields it can not decode as they are, hence
+	// the values are copied back even on an error.
+	err := json.Unmarshal(body, &res)
+
+	m.Took = res.Took
+	m.HitsTotal = res.Hits.Total.Value
+	m.HitsRelation = res.Hits.Total.Relation
+	m.ShardsTotal = res.Shards.Total
+	return err
+}
+
+// ContextWithResponseMeta returns a context asking the transport to hand the
+// parsed response values of the request made with it back to the caller,
+// regardless of the tagging options and the sampling decision. The values
+// can be read through ResponseMetaFromContext once the request is done.
+func ContextWithResponseMeta(ctx context.Context) context.Context {
+	return context.WithValue(ctx, responseMetaKey{}, &ResponseMeta{})
+}
+
+// ResponseMetaFromContext returns the response values parsed by the
+// transport or nil if the context was not created by
+// ContextWithResponseMeta. The context must not be shared by concurrent
+// requests.
+func ResponseMetaFromContext(ctx context.Context) *ResponseMeta {
+	m, _ := ctx.Value(responseMetaKey{}).(*ResponseMeta)
+	return m
+}
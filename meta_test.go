@@ -0,0 +1,82 @@
+package zipkines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestResponseMetaFromContext(t *testing.T) {
+	tracer, err := zipkin.NewTracer(recorder.NewReporter(), zipkin.WithSampler(zipkin.NeverSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"took":12,"_shards":{"total":6},"hits":{"total":274}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+
+	if ResponseMetaFromContext(context.Background()) != nil {
+		t.Errorf("unexpected response meta in empty context")
+	}
+
+	ctx := ContextWithResponseMeta(context.Background())
+	req, _ := http.NewRequest("GET", srv.URL+"/_search", nil)
+	if _, err := transport.RoundTrip(req.WithContext(ctx)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	meta := ResponseMetaFromContext(ctx)
+	if want, have := (ResponseMeta{Took: 12, HitsTotal: 274, ShardsTotal: 6}), *meta; want != have {
+		t.Errorf("unexpected response meta; want %+v, have %+v", want, have)
+	}
+}
+
+func TestResponseMetaHitsRelation(t *testing.T) {
+	m := &ResponseMeta{}
+	if err := m.fill([]byte(`{"took":3,"hits":{"total":{"value":10000,"relation":"gte"}}}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, have := 10000, m.HitsTotal; want != have {
+		t.Errorf("unexpected total hits; want %d, have %d", want, have)
+	}
+
+	if want, have := "gte", m.HitsRelation; want != have {
+		t.Errorf("unexpected total hits relation; want %q, have %q", want, have)
+	}
+}
+
+func TestResponseMetaKeepsTheValuesWhichFailToParse(t *testing.T) {
+	m := &ResponseMeta{Took: 3, HitsTotal: 10, HitsRelation: "eq", ShardsTotal: 2}
+	if err := m.fill([]byte(`{"took":"slow","hits":{"total":274},"_shards":{"total":6}}`)); err == nil {
+		t.Errorf("expected an error for the malformed took")
+	}
+
+	if want, have := (ResponseMeta{Took: 3, HitsTotal: 274, ShardsTotal: 6}), *m; want != have {
+		t.Errorf("unexpected response meta; want %+v, have %+v", want, have)
+	}
+
+	if err := m.fill([]byte(`{"took":`)); err == nil {
+		t.Errorf("expected an error for the truncated body")
+	}
+
+	if want, have := (ResponseMeta{Took: 3, HitsTotal: 274, ShardsTotal: 6}), *m; want != have {
+		t.Errorf("unexpected response meta; want %+v, have %+v", want, have)
+	}
+
+	if err := m.fill([]byte(`{"took":7}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, have := (ResponseMeta{Took: 7, HitsTotal: 274, ShardsTotal: 6}), *m; want != have {
+		t.Errorf("unexpected response meta; want %+v, have %+v", want, have)
+	}
+}
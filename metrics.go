@@ -0,0 +1,143 @@
+package zipkines
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics records the calls regardless of the sampling, keyed by the same
+// operation names as the spans.
+type metrics struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	// owned are the collectors registered by this transport, as opposed to
+	// the ones shared with other transports.
+	owned []prometheus.Collector
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "es_client_requests_total",
+			Help: "Number of requests sent to ES by operation and status code.",
+		}, []string{"operation", "status"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "es_client_request_errors_total",
+			Help: "Number of failed requests sent to ES by operation.",
+		}, []string{"operation"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "es_client_request_duration_seconds",
+			Help:    "Duration of the requests sent to ES by operation.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+	}
+}
+
+// register registers the collectors, reusing the ones already registered by
+// another transport. The collectors registered by the call are unregistered
+// if any of them fails.
+func (m *metrics) register(registerer prometheus.Registerer) error {
+	requests, err := m.registerCollector(registerer, m.requests)
+	if err != nil {
+		m.unregister(registerer)
+		return err
+	}
+	m.requests = requests.(*prometheus.CounterVec)
+
+	errors, err := m.registerCollector(registerer, m.errors)
+	if err != nil {
+		m.unregister(registerer)
+		return err
+	}
+	m.errors = errors.(*prometheus.CounterVec)
+
+	duration, err := m.registerCollector(registerer, m.duration)
+	if err != nil {
+		m.unregister(registerer)
+		return err
+	}
+	m.duration = duration.(*prometheus.HistogramVec)
+	return nil
+}
+
+// registerCollector registers a collector or returns the one already
+// registered.
+func (m *metrics) registerCollector(registerer prometheus.Registerer, c prometheus.Collector) (prometheus.Collector, error) {
+	if err := registerer.Register(c); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, err
+		}
+		return are.ExistingCollector, nil
+	}
+	m.owned = append(m.owned, c)
+	return c, nil
+}
+
+// unregister unregisters the collectors registered by register, leaving the
+// ones shared with other transports.
+func (m *metrics) unregister(registerer prometheus.Registerer) {
+	for _, c := range m.owned {
+		registerer.Unregister(c)
+	}
+	m.owned = nil
+}
+
+// registerMetrics registers the collectors of WithMetrics, if any, keeping
+// the error for ValidateOpts.
+func (r *Transport) registerMetrics() {
+	if r.metricsRegisterer == nil {
+		return
+	}
+
+	m := newMetrics()
+	if err := m.register(r.metricsRegisterer); err != nil {
+		r.metricsErr = err
+		return
+	}
+	r.metrics = m
+}
+
+// recordMetrics records a call, the status is "error" if no response was
+// received. The non successful status codes count as errors according to
+// the error policy.
+func (r *Transport) recordMetrics(req *http.Request, res *http.Response, rtErr error, d time.Duration) {
+	if r.metrics == nil {
+		return
+	}
+
+	operation, ok := r.operationName(req.Method, req.URL.Path)
+	if !ok {
+		operation = req.Method
+	}
+
+	status := "error"
+	failed := rtErr != nil
+	if rtErr == nil {
+		status = strconv.Itoa(res.StatusCode)
+		failed = (res.StatusCode < 200 || res.StatusCode > 299) && r.errorPolicy(req, res.StatusCode)
+	}
+
+	r.metrics.requests.WithLabelValues(operation, status).Inc()
+	if failed {
+		r.metrics.errors.WithLabelValues(operation).Inc()
+	}
+	r.metrics.duration.WithLabelValues(operation).Observe(d.Seconds())
+}
+
+// WithMetrics records Prometheus metrics of every traced call, regardless
+// of the sampling, in the given registerer: the requests count by operation
+// and status code, the errors count and the latency histogram by operation.
+// The operations are named as in "es.operation", or after the method if
+// unknown. The collectors are shared by the transports using the same
+// registerer. A registration failure, e.g. a conflicting collector, is
+// logged by NewTransport and reported by ValidateOpts.
+func WithMetrics(registerer prometheus.Registerer) TraceOpt {
+	return func(r *Transport) {
+		r.metricsRegisterer = registerer
+	}
+}
@@ -0,0 +1,102 @@
+package zipkines
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	tracer, _ := zipkin.NewTracer(recorder.NewReporter(), zipkin.WithSampler(zipkin.NeverSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/missing/_search":
+			rw.WriteHeader(http.StatusNotFound)
+		case "/orders/_doc/1":
+			rw.WriteHeader(http.StatusNotFound)
+		}
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	registry := prometheus.NewRegistry()
+	transport := NewTransport(tracer, WithMetrics(registry))
+	// the collectors are shared by the transports of the same registry
+	other := NewTransport(tracer, WithMetrics(registry))
+	for _, tc := range []struct {
+		transport *Transport
+		path      string
+	}{
+		{transport, "/orders/_search"},
+		{other, "/orders/_search"},
+		{transport, "/missing/_search"},
+		{transport, "/orders/_doc/1"},
+	} {
+		req, _ := http.NewRequest("GET", srv.URL+tc.path, nil)
+		res, err := tc.transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	failing := NewTransport(tracer, WithMetrics(registry), RoundTripper(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})))
+	req, _ := http.NewRequest("GET", srv.URL+"/orders/_search", nil)
+	if _, err := failing.RoundTrip(req); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	m := transport.metrics
+	for _, tc := range []struct {
+		collector prometheus.Collector
+		expected  float64
+	}{
+		{m.requests.WithLabelValues("search", "200"), 2},
+		{m.requests.WithLabelValues("search", "404"), 1},
+		{m.requests.WithLabelValues("search", "error"), 1},
+		{m.requests.WithLabelValues("get", "404"), 1},
+		{m.errors.WithLabelValues("search"), 2},
+		{m.errors.WithLabelValues("get"), 0},
+	} {
+		if want, have := tc.expected, testutil.ToFloat64(tc.collector); want != have {
+			t.Errorf("unexpected metric value; want %v, have %v", want, have)
+		}
+	}
+
+	if want, have := 2, testutil.CollectAndCount(m.duration); want != have {
+		t.Errorf("unexpected duration series; want %d, have %d", want, have)
+	}
+}
+
+func TestMetricsRegistrationFailureIsLogged(t *testing.T) {
+	tracer, _ := zipkin.NewTracer(recorder.NewReporter(), zipkin.WithSampler(zipkin.NeverSample))
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "es_client_requests_total",
+		Help: "A gauge taking the name of the requests counter.",
+	}))
+
+	buf := &bytes.Buffer{}
+	// the logger applies even when passed after the metrics
+	transport := NewTransport(tracer, WithMetrics(registry), WithLogger(log.New(buf, "", 0)))
+	if transport.metrics != nil {
+		t.Error("unexpected metrics")
+	}
+
+	if want, have := "failed to register the metrics", buf.String(); !strings.Contains(have, want) {
+		t.Errorf("unexpected log; want %q in %q", want, have)
+	}
+}
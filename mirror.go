@@ -0,0 +1,99 @@
+package zipkines
+
+import (
+	"sync"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter"
+)
+
+// mirrorSpan forwards every call to the actual span while recording them in
+// a model which is sent to an additional reporter once the span is finished.
+type mirrorSpan struct {
+	zipkin.Span
+	reporter reporter.Reporter
+
+	mu       sync.Mutex
+	model    model.SpanModel
+	finished bool
+}
+
+func newMirrorSpan(span zipkin.Span, rep reporter.Reporter, name string, kind model.Kind, local *model.Endpoint, start time.Time) *mirrorSpan {
+	return &mirrorSpan{
+		Span:     span,
+		reporter: rep,
+		model: model.SpanModel{
+			SpanContext:   span.Context(),
+			Name:          name,
+			Kind:          kind,
+			LocalEndpoint: local,
+			Timestamp:     start,
+			Tags:          map[string]string{},
+		},
+	}
+}
+
+func (s *mirrorSpan) SetName(name string) {
+	s.Span.SetName(name)
+	s.mu.Lock()
+	s.model.Name = name
+	s.mu.Unlock()
+}
+
+func (s *mirrorSpan) SetRemoteEndpoint(e *model.Endpoint) {
+	s.Span.SetRemoteEndpoint(e)
+	s.mu.Lock()
+	s.model.RemoteEndpoint = e
+	s.mu.Unlock()
+}
+
+func (s *mirrorSpan) Annotate(t time.Time, value string) {
+	s.Span.Annotate(t, value)
+	s.mu.Lock()
+	s.model.Annotations = append(s.model.Annotations, model.Annotation{Timestamp: t, Value: value})
+	s.mu.Unlock()
+}
+
+func (s *mirrorSpan) Tag(key, value string) {
+	s.Span.Tag(key, value)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// as in the actual span the first error value is persisted
+	if _, ok := s.model.Tags[key]; ok && key == string(zipkin.TagError) {
+		return
+	}
+	s.model.Tags[key] = value
+}
+
+func (s *mirrorSpan) Finish() {
+	s.Span.Finish()
+	s.report(time.Since(s.model.Timestamp))
+}
+
+func (s *mirrorSpan) FinishedWithDuration(d time.Duration) {
+	s.Span.FinishedWithDuration(d)
+	s.report(d)
+}
+
+func (s *mirrorSpan) report(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished || !isSampled(s.Span) {
+		return
+	}
+	s.finished = true
+	s.model.Duration = d
+	s.reporter.Send(s.model)
+}
+
+// WithAdditionalReporter sends the ES client spans to an additional reporter,
+// e.g. a local file or kafka reporter for long term query analytics, while
+// the tracer keeps reporting them to its own reporter. Only sampled spans
+// are sent.
+func WithAdditionalReporter(rep reporter.Reporter) TraceOpt {
+	return func(r *Transport) {
+		r.additionalReporter = rep
+	}
+}
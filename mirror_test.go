@@ -0,0 +1,58 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestAdditionalReporter(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(500)
+	}))
+	defer srv.Close()
+
+	additional := recorder.NewReporter()
+	transport := NewTransport(tracer, WithAdditionalReporter(additional))
+	req, _ := http.NewRequest("GET", srv.URL+"/logs/_search", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	mirrored := additional.Flush()
+	if want, have := 1, len(mirrored); want != have {
+		t.Fatalf("unexpected mirrored spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := spans[0].SpanContext, mirrored[0].SpanContext; want.ID != have.ID || want.TraceID != have.TraceID {
+		t.Errorf("unexpected mirrored span context; want %+v, have %+v", want, have)
+	}
+
+	if want, have := spans[0].Name, mirrored[0].Name; want != have {
+		t.Errorf("unexpected mirrored span name; want %q, have %q", want, have)
+	}
+
+	if want, have := len(spans[0].Tags), len(mirrored[0].Tags); want != have {
+		t.Errorf("unexpected mirrored tags; want %v, have %v", spans[0].Tags, mirrored[0].Tags)
+	}
+
+	for key, val := range spans[0].Tags {
+		if want, have := val, mirrored[0].Tags[key]; want != have {
+			t.Errorf("unexpected mirrored tag %q; want %q, have %q", key, want, have)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package zipkines
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// msearchIndices returns the index targeted by every search of a multi
+// search NDJSON payload, that is the header line plus the body line of each
+// search. Searches whose header does not name an index target the default
+// one, the one of the path.
+func msearchIndices(body []byte, defaultIndex string) ([]string, error) {
+	var indices []string
+	r := bufio.NewReader(bytes.NewReader(body))
+	for {
+		header, err := readBulkLine(r)
+		if err == io.EOF {
+			return indices, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		h := struct {
+			Index json.RawMessage `json:"index"`
+		}{}
+		if err := json.Unmarshal(header, &h); err != nil {
+			return nil, fmt.Errorf("invalid msearch header line: %v", err)
+		}
+
+		index := defaultIndex
+		var single string
+		var multiple []string
+		if json.Unmarshal(h.Index, &single) == nil && single != "" {
+			index = single
+		} else if json.Unmarshal(h.Index, &multiple) == nil && len(multiple) > 0 {
+			index = strings.Join(multiple, ",")
+		}
+		indices = append(indices, index)
+
+		if _, err := readBulkLine(r); err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+}
+
+type msearchResponse struct {
+	Responses []struct {
+		Took     *int64 `json:"took"`
+		TimedOut bool   `json:"timed_out"`
+		Status   int    `json:"status"`
+		Hits     struct {
+			Total hitsTotal `json:"total"`
+		} `json:"hits"`
+		Error *struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	} `json:"responses"`
+}
+
+// emitMSearchSpans emits a child span of the multi search span for each of
+// its searches. The searches are run concurrently by ES hence their spans
+// start with the parent span and last for their took.
+func (r *Transport) emitMSearchSpans(parent zipkin.Span, indices []string, body []byte, start time.Time) error {
+	res := msearchResponse{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	for i, item := range res.Responses {
+		span := r.tracer.StartSpan(r.spanName("es/msearch.item"), zipkin.Parent(parent.Context()), zipkin.StartTime(start))
+		span.Tag("es.msearch.seq", fmt.Sprintf("%d", i+1))
+		if i < len(indices) && indices[i] != "" {
+			TagESIndex.Set(span, indices[i])
+		}
+		if item.Status != 0 {
+			zipkin.TagHTTPStatusCode.Set(span, fmt.Sprintf("%d", item.Status))
+		}
+
+		switch {
+		case item.Error != nil:
+			zipkin.TagError.Set(span, item.Error.Type)
+		case item.TimedOut:
+			zipkin.TagError.Set(span, "timed_out")
+		default:
+			tagHitsTotal(span, item.Hits.Total)
+		}
+
+		var took time.Duration
+		if item.Took != nil {
+			span.Tag("es.took_ms", fmt.Sprintf("%d", *item.Took))
+			took = time.Duration(*item.Took) * time.Millisecond
+		}
+		span.FinishedWithDuration(took)
+	}
+	return nil
+}
+
+// WithMSearchChildSpans emits a child span named "es/msearch.item" for each
+// of the searches of the multi search requests, tagged with its sequence
+// number, target index, took, total hits and error, as parsed from the
+// request and response bodies.
+func WithMSearchChildSpans() TraceOpt {
+	return func(r *Transport) {
+		r.msearchChildSpans = true
+	}
+}
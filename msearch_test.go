@@ -0,0 +1,96 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestMSearchChildSpans(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"took":12,"responses":[
+			{"took":5,"timed_out":false,"hits":{"total":{"value":3,"relation":"eq"}},"status":200},
+			{"error":{"type":"index_not_found_exception"},"status":404},
+			{"took":10,"timed_out":false,"hits":{"total":{"value":7,"relation":"eq"}},"status":200}
+		]}`))
+	}))
+	defer srv.Close()
+
+	payload := `{}
+{"query":{"match_all":{}}}
+{"index":"metrics"}
+{"query":{"match_all":{}}}
+{"index":["a","b"]}
+{"query":{"match_all":{}}}
+`
+
+	transport := NewTransport(tracer, WithMSearchChildSpans())
+	req, _ := http.NewRequest("POST", srv.URL+"/logs/_msearch", strings.NewReader(payload))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 4, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	parent := spans[3]
+	if want, have := "es/_msearch", parent.Name; want != have {
+		t.Fatalf("unexpected parent span name; want %q, have %q", want, have)
+	}
+
+	expectedTags := []map[string]string{
+		{"es.msearch.seq": "1", "es.index": "logs", "es.took_ms": "5", "es.hits.total": "3", "error": ""},
+		{"es.msearch.seq": "2", "es.index": "metrics", "http.status_code": "404", "error": "index_not_found_exception"},
+		{"es.msearch.seq": "3", "es.index": "a,b", "es.took_ms": "10", "es.hits.total": "7"},
+	}
+	for i, tags := range expectedTags {
+		if want, have := "es/msearch.item", spans[i].Name; want != have {
+			t.Errorf("unexpected span name; want %q, have %q", want, have)
+		}
+
+		if spans[i].ParentID == nil || *spans[i].ParentID != parent.ID {
+			t.Errorf("expected span %d to be child of the msearch span", i)
+		}
+
+		for key, val := range tags {
+			if want, have := val, spans[i].Tags[key]; want != have {
+				t.Errorf("unexpected %q tag for span %d; want %q, have %q", key, i, want, have)
+			}
+		}
+	}
+}
+
+func TestMSearchChildSpansStartWithTheParentSpan(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"took":12,"responses":[{"took":5,"hits":{"total":3},"status":200}]}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithMSearchChildSpans(), WithClock(func() time.Time { return time.Unix(0, 0) }))
+	req, _ := http.NewRequest("POST", srv.URL+"/logs/_msearch", strings.NewReader("{}\n{\"query\":{\"match_all\":{}}}\n"))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := spans[1].Timestamp, spans[0].Timestamp; !want.Equal(have) {
+		t.Errorf("unexpected child span start; want %s, have %s", want, have)
+	}
+}
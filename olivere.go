@@ -0,0 +1,56 @@
+package zipkines
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/olivere/elastic/v7"
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// elasticClientTransport is the transport wired into the olivere/elastic
+// clients. The health checks and the sniffing made by the client on its own
+// go straight to the parent transport.
+type elasticClientTransport struct {
+	*Transport
+}
+
+func (t elasticClientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isElasticClientHousekeeping(req) {
+		return t.parent.RoundTrip(req)
+	}
+	return t.Transport.RoundTrip(req)
+}
+
+// isElasticClientHousekeeping tells whether the request is one of the health
+// checks, i.e. `HEAD /` against every node, or one of the sniffs, i.e.
+// `GET /_nodes/http`, made periodically by the olivere/elastic clients.
+func isElasticClientHousekeeping(req *http.Request) bool {
+	path := strings.Trim(req.URL.Path, "/")
+	return (req.Method == "HEAD" && path == "") || (req.Method == "GET" && path == "_nodes/http")
+}
+
+// NewElasticClientOptions returns the options to trace the calls made by an
+// olivere/elastic v7 client:
+//
+//	client, err := elastic.NewClient(append(
+//		zipkines.NewElasticClientOptions(tracer),
+//		elastic.SetURL("http://es-01:9200"),
+//	)...)
+//
+// The health checks and sniffs made periodically by the client are not
+// traced, hence neither are the explicit pings made with `HEAD /`. As a
+// sniffing client spreads the calls over the discovered nodes, the URL of
+// the node each request actually hit is tagged as "es.node.url".
+func NewElasticClientOptions(tracer *zipkin.Tracer, opts ...TraceOpt) []elastic.ClientOptionFunc {
+	t := NewTransport(tracer, append(opts, withTagNodeURL())...)
+	return []elastic.ClientOptionFunc{
+		elastic.SetHttpClient(&http.Client{Transport: elasticClientTransport{t}}),
+	}
+}
+
+func withTagNodeURL() TraceOpt {
+	return func(r *Transport) {
+		r.tagNodeURL = true
+	}
+}
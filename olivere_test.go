@@ -0,0 +1,87 @@
+package zipkines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/olivere/elastic/v7"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestElasticClientOptions(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var healthchecks int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		if req.Method == "HEAD" {
+			healthchecks++
+			return
+		}
+		rw.Write([]byte(`{"took":1,"hits":{"total":{"value":3,"relation":"eq"},"hits":[]}}`))
+	}))
+	defer srv.Close()
+
+	client, err := elastic.NewClient(append(
+		NewElasticClientOptions(tracer, WithTagTotalHits()),
+		elastic.SetURL(srv.URL),
+		elastic.SetSniff(false),
+	)...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer client.Stop()
+
+	if _, err := client.Search("logs").Do(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if healthchecks == 0 {
+		t.Error("expected the client to health check the node")
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "/logs/_search", spans[0].Tags["http.path"]; want != have {
+		t.Errorf("unexpected path; want %q, have %q", want, have)
+	}
+
+	if want, have := srv.URL, spans[0].Tags["es.node.url"]; want != have {
+		t.Errorf("unexpected node URL; want %q, have %q", want, have)
+	}
+
+	if want, have := "3", spans[0].Tags["es.hits.total"]; want != have {
+		t.Errorf("unexpected total hits; want %q, have %q", want, have)
+	}
+}
+
+func TestElasticClientHousekeeping(t *testing.T) {
+	testCases := []struct {
+		method   string
+		path     string
+		expected bool
+	}{
+		{"HEAD", "", true},
+		{"HEAD", "/", true},
+		{"GET", "/_nodes/http", true},
+		{"GET", "/", false},
+		{"HEAD", "/logs", false},
+	}
+
+	for _, tc := range testCases {
+		req, _ := http.NewRequest(tc.method, "http://localhost:9200"+tc.path, nil)
+		if want, have := tc.expected, isElasticClientHousekeeping(req); want != have {
+			t.Errorf("unexpected housekeeping for %s %q; want %t, have %t", tc.method, tc.path, want, have)
+		}
+	}
+}
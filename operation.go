@@ -0,0 +1,34 @@
+package zipkines
+
+import (
+	"context"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+)
+
+type operationKey struct{}
+
+// StartOperation starts a local span covering the whole lifecycle of a long
+// running ES operation such as a snapshot restore, a reindex or a forcemerge.
+// The returned context traces the calls made with it as children of such
+// span. The span context can be carried to the code polling the operation
+// progress through the tasks or recovery APIs and passed to
+// ContextWithOperation so the polls land in the same trace tree. The caller
+// is responsible for finishing the span once the operation completes.
+func StartOperation(ctx context.Context, tracer *zipkin.Tracer, name string) (zipkin.Span, context.Context) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "es/operation."+name)
+	return span, ContextWithOperation(ctx, span.Context())
+}
+
+// ContextWithOperation returns a context whose ES calls are traced as
+// children of the given span context, regardless of any span present in the
+// context.
+func ContextWithOperation(ctx context.Context, sc model.SpanContext) context.Context {
+	return context.WithValue(ctx, operationKey{}, sc)
+}
+
+func operationFromContext(ctx context.Context) (model.SpanContext, bool) {
+	sc, ok := ctx.Value(operationKey{}).(model.SpanContext)
+	return sc, ok
+}
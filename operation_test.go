@@ -0,0 +1,55 @@
+package zipkines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestOperationPollsLandInTheSameTrace(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+
+	op, ctx := StartOperation(context.Background(), tracer, "restore")
+	req, _ := http.NewRequest("POST", srv.URL+"/_snapshot/repo/snap/_restore", nil)
+	if _, err := transport.RoundTrip(req.WithContext(ctx)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the poll happens somewhere else with an unrelated active span
+	other := tracer.StartSpan("poller")
+	pollCtx := ContextWithOperation(zipkin.NewContext(context.Background(), other), op.Context())
+	req, _ = http.NewRequest("GET", srv.URL+"/_recovery", nil)
+	if _, err := transport.RoundTrip(req.WithContext(pollCtx)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	op.Finish()
+
+	spans := reporter.Flush()
+	if want, have := 3, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for _, span := range spans[:2] {
+		if want, have := op.Context().TraceID, span.TraceID; want != have {
+			t.Errorf("unexpected trace ID; want %s, have %s", want, have)
+		}
+		if span.ParentID == nil || *span.ParentID != op.Context().ID {
+			t.Errorf("unexpected parent ID for span %q", span.Name)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package zipkines
+
+import "strings"
+
+// Endpoint maps a method and path pattern of the ES REST API to a canonical
+// operation name as used by the official clients, e.g. "indices.create".
+// Pattern segments are either literals or placeholders between braces:
+// `{index}` and `{id}` match any segment not starting with an underscore and
+// `{api}` matches any segment, replacing the `{api}` in the name.
+type Endpoint struct {
+	// Method is the HTTP method or `*` to match any.
+	Method    string
+	Pattern   string
+	Operation string
+}
+
+// defaultEndpoints are evaluated in order, the first match wins.
+var defaultEndpoints = compileEndpoints([]Endpoint{
+	{"GET", "", "info"},
+	{"HEAD", "", "ping"},
+
+	{"*", "_search", "search"},
+	{"*", "{index}/_search", "search"},
+	{"DELETE", "_search/scroll", "clear_scroll"},
+	{"DELETE", "_search/scroll/{id}", "clear_scroll"},
+	{"*", "_search/scroll", "scroll"},
+	{"*", "_search/scroll/{id}", "scroll"},
+	{"*", "_msearch", "msearch"},
+	{"*", "{index}/_msearch", "msearch"},
+	{"*", "_count", "count"},
+	{"*", "{index}/_count", "count"},
+	{"*", "_bulk", "bulk"},
+	{"*", "{index}/_bulk", "bulk"},
+	{"*", "_mget", "mget"},
+	{"*", "{index}/_mget", "mget"},
+	{"*", "_mtermvectors", "mtermvectors"},
+	{"*", "{index}/_mtermvectors", "mtermvectors"},
+	{"*", "_field_caps", "field_caps"},
+	{"*", "{index}/_field_caps", "field_caps"},
+	{"*", "_search/template", "search_template"},
+	{"*", "{index}/_search/template", "search_template"},
+	{"*", "_msearch/template", "msearch_template"},
+	{"*", "{index}/_msearch/template", "msearch_template"},
+	{"*", "_validate/query", "indices.validate_query"},
+	{"*", "{index}/_validate/query", "indices.validate_query"},
+	{"POST", "{index}/_pit", "open_point_in_time"},
+	{"DELETE", "_pit", "close_point_in_time"},
+	{"*", "_reindex", "reindex"},
+	{"*", "{index}/_update_by_query", "update_by_query"},
+	{"*", "{index}/_delete_by_query", "delete_by_query"},
+
+	{"POST", "{index}/_doc", "index"},
+	{"GET", "{index}/_doc/{id}", "get"},
+	{"HEAD", "{index}/_doc/{id}", "exists"},
+	{"DELETE", "{index}/_doc/{id}", "delete"},
+	{"*", "{index}/_doc/{id}", "index"},
+	{"*", "{index}/_create/{id}", "create"},
+	{"*", "{index}/_update/{id}", "update"},
+	{"HEAD", "{index}/_source/{id}", "exists_source"},
+	{"*", "{index}/_source/{id}", "get_source"},
+	{"*", "{index}/_explain/{id}", "explain"},
+	{"*", "{index}/_termvectors", "termvectors"},
+	{"*", "{index}/_termvectors/{id}", "termvectors"},
+
+	{"DELETE", "_async_search/{id}", "async_search.delete"},
+	{"*", "_async_search/status/{id}", "async_search.status"},
+	{"*", "_async_search/{id}", "async_search.get"},
+	{"POST", "_async_search", "async_search.submit"},
+	{"POST", "{index}/_async_search", "async_search.submit"},
+
+	{"*", "_cluster/health", "cluster.health"},
+	{"*", "_cluster/health/{index}", "cluster.health"},
+	{"*", "_cluster/state", "cluster.state"},
+	{"*", "_cluster/stats", "cluster.stats"},
+	{"PUT", "_cluster/settings", "cluster.put_settings"},
+	{"*", "_cluster/settings", "cluster.get_settings"},
+	{"*", "_nodes", "nodes.info"},
+	{"*", "_nodes/stats", "nodes.stats"},
+	{"*", "_cat/{api}", "cat.{api}"},
+	{"*", "_tasks", "tasks.list"},
+	{"*", "_tasks/{id}", "tasks.get"},
+	{"*", "_tasks/{id}/_cancel", "tasks.cancel"},
+
+	{"PUT", "{index}", "indices.create"},
+	{"DELETE", "{index}", "indices.delete"},
+	{"HEAD", "{index}", "indices.exists"},
+	{"GET", "{index}", "indices.get"},
+	{"PUT", "{index}/_mapping", "indices.put_mapping"},
+	{"*", "{index}/_mapping", "indices.get_mapping"},
+	{"PUT", "{index}/_settings", "indices.put_settings"},
+	{"*", "{index}/_settings", "indices.get_settings"},
+	{"*", "_aliases", "indices.update_aliases"},
+	{"*", "{index}/_refresh", "indices.refresh"},
+	{"*", "_refresh", "indices.refresh"},
+	{"*", "{index}/_flush", "indices.flush"},
+	{"*", "_flush", "indices.flush"},
+	{"*", "{index}/_forcemerge", "indices.forcemerge"},
+	{"*", "_forcemerge", "indices.forcemerge"},
+	{"*", "_stats", "indices.stats"},
+	{"*", "_stats/{api}", "indices.stats"},
+	{"*", "{index}/_stats", "indices.stats"},
+	{"*", "{index}/_stats/{api}", "indices.stats"},
+	{"*", "_segments", "indices.segments"},
+	{"*", "{index}/_segments", "indices.segments"},
+	{"*", "_analyze", "indices.analyze"},
+	{"*", "{index}/_analyze", "indices.analyze"},
+
+	{"*", "_scripts/painless/_execute", "scripts_painless_execute"},
+	{"PUT", "_ingest/pipeline/{id}", "ingest.put_pipeline"},
+	{"DELETE", "_ingest/pipeline/{id}", "ingest.delete_pipeline"},
+	{"*", "_ingest/pipeline", "ingest.get_pipeline"},
+	{"*", "_ingest/pipeline/{id}", "ingest.get_pipeline"},
+	{"*", "_ingest/pipeline/_simulate", "ingest.simulate"},
+	{"*", "_ingest/pipeline/{id}/_simulate", "ingest.simulate"},
+	{"PUT", "_enrich/policy/{id}", "enrich.put_policy"},
+	{"DELETE", "_enrich/policy/{id}", "enrich.delete_policy"},
+	{"*", "_enrich/policy", "enrich.get_policy"},
+	{"*", "_enrich/policy/{id}", "enrich.get_policy"},
+	{"*", "_enrich/policy/{id}/_execute", "enrich.execute_policy"},
+	{"*", "{index}/_ccr/follow", "ccr.follow"},
+	{"*", "{index}/_ccr/pause_follow", "ccr.pause_follow"},
+	{"*", "{index}/_ccr/resume_follow", "ccr.resume_follow"},
+	{"*", "{index}/_ccr/unfollow", "ccr.unfollow"},
+	{"*", "{index}/_ccr/stats", "ccr.follow_stats"},
+	{"*", "{index}/_ccr/info", "ccr.follow_info"},
+	{"*", "{index}/_ccr/forget_follower", "ccr.forget_follower"},
+	{"*", "_ccr/stats", "ccr.stats"},
+	{"*", "_ccr/auto_follow", "ccr.get_auto_follow_pattern"},
+	{"PUT", "_ccr/auto_follow/{id}", "ccr.put_auto_follow_pattern"},
+	{"DELETE", "_ccr/auto_follow/{id}", "ccr.delete_auto_follow_pattern"},
+	{"*", "_ccr/auto_follow/{id}", "ccr.get_auto_follow_pattern"},
+	{"PUT", "_snapshot/{id}/{id}", "snapshot.create"},
+	{"DELETE", "_snapshot/{id}/{id}", "snapshot.delete"},
+	{"*", "_snapshot/{id}/{id}", "snapshot.get"},
+	{"*", "_snapshot/{id}/{id}/_restore", "snapshot.restore"},
+	{"*", "_security/{api}", "security.{api}"},
+	{"*", "_security/{api}/{id}", "security.{api}"},
+	{"*", "_plugins/_security/{api}", "security.{api}"},
+	{"*", "_plugins/_security/api/{api}", "security.{api}"},
+	{"*", "_plugins/_security/api/{api}/{id}", "security.{api}"},
+	{"*", "_opendistro/_security/{api}", "security.{api}"},
+	{"*", "_opendistro/_security/api/{api}", "security.{api}"},
+	{"*", "_opendistro/_security/api/{api}/{id}", "security.{api}"},
+})
+
+type compiledEndpoint struct {
+	method string
+	pieces []string
+	name   string
+}
+
+func compileEndpoints(endpoints []Endpoint) []compiledEndpoint {
+	compiled := make([]compiledEndpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		compiled = append(compiled, compiledEndpoint{
+			method: e.Method,
+			pieces: splitPath(e.Pattern),
+			name:   e.Operation,
+		})
+	}
+	return compiled
+}
+
+// splitPath splits a path in its segments, an empty path has none. Empty
+// segments, e.g. from duplicated slashes, are dropped as ES does.
+func splitPath(path string) []string {
+	var pieces []string
+	for _, piece := range strings.Split(path, "/") {
+		if piece != "" {
+			pieces = append(pieces, piece)
+		}
+	}
+	return pieces
+}
+
+// EndpointMatch is the endpoint addressed by a request along with the
+// parameters extracted from its path.
+type EndpointMatch struct {
+	Operation string
+	// Index and ID are the segments matching `{index}` and `{id}`, if any.
+	Index string
+	ID    string
+}
+
+// match returns the operation name and the path parameters if the endpoint
+// matches the request.
+func (e compiledEndpoint) match(method string, pieces []string) (EndpointMatch, bool) {
+	if (e.method != "*" && e.method != method) || len(e.pieces) != len(pieces) {
+		return EndpointMatch{}, false
+	}
+
+	m := EndpointMatch{Operation: e.name}
+	for i, p := range e.pieces {
+		switch p {
+		case "{index}", "{id}":
+			if pieces[i][:1] == "_" {
+				return EndpointMatch{}, false
+			}
+			if p == "{index}" {
+				m.Index = pieces[i]
+			} else {
+				m.ID = pieces[i]
+			}
+		case "{api}":
+			m.Operation = strings.Replace(m.Operation, "{api}", pieces[i], 1)
+		default:
+			if p != pieces[i] {
+				return EndpointMatch{}, false
+			}
+		}
+	}
+	return m, true
+}
+
+// OperationClassifier names the operation addressed by a request, e.g.
+// "search", or returns false if it does not know it. The operation names
+// drive the span names, the "es.operation" tag, the API families and the
+// latency histograms.
+type OperationClassifier interface {
+	Classify(method, path string) (string, bool)
+}
+
+// OperationClassifierFunc adapts a function to an OperationClassifier.
+type OperationClassifierFunc func(method, path string) (string, bool)
+
+func (f OperationClassifierFunc) Classify(method, path string) (string, bool) {
+	return f(method, path)
+}
+
+// ClassifierChain asks its classifiers in order, the first one knowing the
+// operation wins.
+type ClassifierChain []OperationClassifier
+
+func (c ClassifierChain) Classify(method, path string) (string, bool) {
+	for _, classifier := range c {
+		if name, ok := classifier.Classify(method, path); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+type endpointClassifier []compiledEndpoint
+
+func (c endpointClassifier) Classify(method, path string) (string, bool) {
+	m, ok := c.classifyEndpoint(method, path)
+	return m.Operation, ok
+}
+
+func (c endpointClassifier) classifyEndpoint(method, path string) (EndpointMatch, bool) {
+	pieces := splitPath(path)
+	for _, e := range c {
+		if m, ok := e.match(method, pieces); ok {
+			return m, true
+		}
+	}
+	return EndpointMatch{}, false
+}
+
+// ClassifyEndpoint returns the endpoint of the ES REST API addressed by a
+// request along with its path parameters, e.g. the index and the document
+// ID of "PUT /orders/_doc/1", or false if it is unknown.
+func ClassifyEndpoint(method, path string) (EndpointMatch, bool) {
+	return endpointClassifier(defaultEndpoints).classifyEndpoint(method, path)
+}
+
+// legacySpanNames are the span names of the operations which were named
+// after the endpoint in their path before the canonical names existed, e.g.
+// "es/_search", kept so the default span names do not change.
+var legacySpanNames = map[string]string{
+	"search":                   "es/_search",
+	"msearch":                  "es/_msearch",
+	"count":                    "es/_count",
+	"bulk":                     "es/_bulk",
+	"mget":                     "es/_mget",
+	"mtermvectors":             "es/_mtermvectors",
+	"field_caps":               "es/_field_caps",
+	"open_point_in_time":       "es/_pit",
+	"reindex":                  "es/_reindex",
+	"update_by_query":          "es/_update_by_query",
+	"delete_by_query":          "es/_delete_by_query",
+	"index":                    "es/_doc",
+	"exists":                   "es/doc.exists",
+	"termvectors":              "es/_termvectors",
+	"async_search.submit":      "es/_async_search",
+	"async_search.get":         "es/_async_search",
+	"async_search.status":      "es/_async_search",
+	"async_search.delete":      "es/_async_search",
+	"nodes.info":               "es/_nodes",
+	"tasks.list":               "es/_tasks",
+	"tasks.get":                "es/_tasks",
+	"tasks.cancel":             "es/_tasks",
+	"indices.exists":           "es/index.exists",
+	"indices.get_mapping":      "es/_mapping",
+	"indices.get_settings":     "es/_settings",
+	"indices.update_aliases":   "es/_aliases",
+	"indices.refresh":          "es/_refresh",
+	"indices.flush":            "es/_flush",
+	"indices.forcemerge":       "es/_forcemerge",
+	"indices.stats":            "es/_stats",
+	"indices.segments":         "es/_segments",
+	"indices.analyze":          "es/_analyze",
+	"scripts_painless_execute": "es/painless.execute",
+	"ccr.follow_stats":         "es/ccr.stats",
+	"ccr.follow_info":          "es/ccr.info",
+}
+
+// defaultSpanName returns the default span name of an operation, which is
+// its canonical name unless it predates it, see legacySpanNames.
+func defaultSpanName(operation string) string {
+	if name, ok := legacySpanNames[operation]; ok {
+		return name
+	}
+	return "es/" + operation
+}
+
+// NewEndpointClassifier returns a classifier matching the given endpoints in
+// order, e.g. the ones of an in-house plugin:
+//
+//	NewEndpointClassifier(Endpoint{"POST", "{index}/_myplugin/run", "myplugin.run"})
+func NewEndpointClassifier(endpoints ...Endpoint) OperationClassifier {
+	return endpointClassifier(compileEndpoints(endpoints))
+}
+
+// DefaultOperationClassifier knows the endpoints of the ES REST API and the
+// OpenSearch security plugin.
+var DefaultOperationClassifier OperationClassifier = endpointClassifier(defaultEndpoints)
+
+// operationName returns the canonical name of the operation addressed by a
+// request according to the default classifier, or false if it is unknown.
+func operationName(method, path string) (string, bool) {
+	return DefaultOperationClassifier.Classify(method, path)
+}
+
+// operationName returns the name of the operation addressed by a request
+// according to the classifiers of the transport.
+func (r *Transport) operationName(method, path string) (string, bool) {
+	m, ok := r.classify(method, path)
+	return m.Operation, ok
+}
+
+// classify returns the endpoint addressed by a request according to the
+// classifiers of the transport. The path parameters are only known for the
+// endpoints matched by the default classifier and the ones returned by
+// NewEndpointClassifier.
+func (r *Transport) classify(method, path string) (EndpointMatch, bool) {
+	if r.classifier == nil {
+		return ClassifyEndpoint(method, path)
+	}
+	return classifyEndpointWith(r.classifier, method, path)
+}
+
+func classifyEndpointWith(c OperationClassifier, method, path string) (EndpointMatch, bool) {
+	switch c := c.(type) {
+	case endpointClassifier:
+		return c.classifyEndpoint(method, path)
+	case ClassifierChain:
+		for _, classifier := range c {
+			if m, ok := classifyEndpointWith(classifier, method, path); ok {
+				return m, true
+			}
+		}
+		return EndpointMatch{}, false
+	}
+
+	operation, ok := c.Classify(method, path)
+	return EndpointMatch{Operation: operation}, ok
+}
+
+// WithOperationClassifiers registers classifiers asked in order before the
+// default one, e.g. for the endpoints of in-house plugins. The operations
+// they return name the spans and drive the operation specific tagging, the
+// error policy and the metrics.
+func WithOperationClassifiers(classifiers ...OperationClassifier) TraceOpt {
+	return func(r *Transport) {
+		chain := append(ClassifierChain(nil), classifiers...)
+		r.classifier = append(chain, DefaultOperationClassifier)
+	}
+}
+
+// WithCanonicalSpanNames names the spans after the canonical operation names
+// used by the official clients and the ES documentation, e.g.
+// "es/indices.create" or "es/cluster.health", for the known endpoints.
+func WithCanonicalSpanNames() TraceOpt {
+	return func(r *Transport) {
+		r.opts.CanonicalSpanNames = true
+	}
+}
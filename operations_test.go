@@ -0,0 +1,272 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestOperationName(t *testing.T) {
+	testCases := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{"GET", "/", "info"},
+		{"POST", "/logs/_search", "search"},
+		{"DELETE", "/_search/scroll", "clear_scroll"},
+		{"PUT", "/logs", "indices.create"},
+		{"HEAD", "/logs/_doc/1", "exists"},
+		{"PUT", "/logs/_doc/1", "index"},
+		{"GET", "/_cluster/health/logs", "cluster.health"},
+		{"GET", "/_cat/indices", "cat.indices"},
+		{"POST", "/_snapshot/repo/snap/_restore", "snapshot.restore"},
+		{"GET", "/logs/_unknown_endpoint", ""},
+	}
+
+	for _, tc := range testCases {
+		name, ok := operationName(tc.method, tc.path)
+		if want, have := tc.expected != "", ok; want != have {
+			t.Errorf("unexpected match for %s %s; want %t, have %t", tc.method, tc.path, want, have)
+		}
+		if want, have := tc.expected, name; want != have {
+			t.Errorf("unexpected operation name for %s %s; want %q, have %q", tc.method, tc.path, want, have)
+		}
+	}
+}
+
+func TestCanonicalSpanNames(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithCanonicalSpanNames())
+	req, _ := http.NewRequest("GET", srv.URL+"/_cluster/health", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "es/cluster.health", spans[0].Name; want != have {
+		t.Errorf("unexpected span name; want %q, have %q", want, have)
+	}
+
+	if want, have := "cluster.health", spans[0].Tags["es.operation"]; want != have {
+		t.Errorf("unexpected operation tag; want %q, have %q", want, have)
+	}
+}
+
+func TestOperationClassifiers(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(
+		tracer,
+		WithCanonicalSpanNames(),
+		WithOperationClassifiers(
+			NewEndpointClassifier(Endpoint{"POST", "{index}/_myplugin/run", "myplugin.run"}),
+			OperationClassifierFunc(func(method, path string) (string, bool) {
+				return "myplugin.status", method == "GET" && path == "/_myplugin/status"
+			}),
+		),
+		WithDisabledAPIFamilies(APIFamilyCat),
+	)
+
+	for _, tc := range []struct {
+		method string
+		path   string
+	}{
+		{"POST", "/logs/_myplugin/run"},
+		{"GET", "/_myplugin/status"},
+		{"POST", "/logs/_search"},
+		{"GET", "/_cat/indices"},
+	} {
+		req, _ := http.NewRequest(tc.method, srv.URL+tc.path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 3, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for i, expected := range []string{"myplugin.run", "myplugin.status", "search"} {
+		if want, have := expected, spans[i].Tags["es.operation"]; want != have {
+			t.Errorf("unexpected operation; want %q, have %q", want, have)
+		}
+		if want, have := "es/"+expected, spans[i].Name; want != have {
+			t.Errorf("unexpected span name; want %q, have %q", want, have)
+		}
+	}
+}
+
+func TestOperationClassifiersDriveTheTagging(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/legacy/_lookup/1" {
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"found":false}`))
+			return
+		}
+		rw.Write([]byte(`{"count":12}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(
+		tracer,
+		WithTagTotalHits(),
+		WithOperationClassifiers(
+			NewEndpointClassifier(Endpoint{"GET", "{index}/_lookup/{id}", "get"}),
+			OperationClassifierFunc(func(method, path string) (string, bool) {
+				return "count", path == "/_tally"
+			}),
+		),
+	)
+
+	for _, path := range []string{"/legacy/_lookup/1", "/_tally"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	// a 404 is a plain answer for the document gets
+	if _, ok := spans[0].Tags["error"]; ok {
+		t.Errorf("unexpected error tag: %q", spans[0].Tags["error"])
+	}
+
+	if want, have := "12", spans[1].Tags["es.count"]; want != have {
+		t.Errorf("unexpected count; want %q, have %q", want, have)
+	}
+}
+
+func TestClassifyEndpoint(t *testing.T) {
+	testCases := []struct {
+		method, path string
+		expected     EndpointMatch
+		ok           bool
+	}{
+		{"PUT", "/orders/_doc/1", EndpointMatch{Operation: "index", Index: "orders", ID: "1"}, true},
+		{"GET", "/orders/_search", EndpointMatch{Operation: "search", Index: "orders"}, true},
+		{"GET", "/", EndpointMatch{Operation: "info"}, true},
+		{"GET", "//orders//_search/", EndpointMatch{Operation: "search", Index: "orders"}, true},
+		{"GET", "/_async_search/status/FmRldE8zREVE", EndpointMatch{Operation: "async_search.status", ID: "FmRldE8zREVE"}, true},
+		{"GET", "/orders/_unknown_plugin_api", EndpointMatch{}, false},
+	}
+
+	for _, tc := range testCases {
+		m, ok := ClassifyEndpoint(tc.method, tc.path)
+		if want, have := tc.ok, ok; want != have {
+			t.Errorf("unexpected classification of %s %s; want %t, have %t", tc.method, tc.path, want, have)
+		}
+
+		if want, have := tc.expected, m; want != have {
+			t.Errorf("unexpected endpoint of %s %s; want %+v, have %+v", tc.method, tc.path, want, have)
+		}
+	}
+}
+
+func TestDefaultSpanNames(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithOperationClassifiers(
+		NewEndpointClassifier(Endpoint{"POST", "{index}/_myplugin/run", "myplugin.run"}),
+	))
+
+	testCases := []struct {
+		method, path string
+		expectedName string
+	}{
+		{"POST", "/logs/_search", "es/_search"},
+		{"HEAD", "/logs/_doc/1", "es/doc.exists"},
+		{"GET", "/_tasks/abc:1", "es/_tasks"},
+		{"GET", "/_cluster/health", "es/cluster.health"},
+		{"POST", "/logs/_myplugin/run", "es/myplugin.run"},
+		{"GET", "/logs/_unknown_endpoint", "es/GET"},
+	}
+
+	for _, tc := range testCases {
+		req, _ := http.NewRequest(tc.method, srv.URL+tc.path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+
+		spans := reporter.Flush()
+		if want, have := 1, len(spans); want != have {
+			t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+		}
+
+		if want, have := tc.expectedName, spans[0].Name; want != have {
+			t.Errorf("unexpected span name for %s %s; want %q, have %q", tc.method, tc.path, want, have)
+		}
+	}
+}
+
+func TestEndpointSpanNameOnEmptySegments(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+	for _, path := range []string{"/", "//", "/orders//_search/"} {
+		req, _ := http.NewRequest("POST", srv.URL+path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 3, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for i, name := range []string{"es/POST", "es/POST", "es/_search"} {
+		if want, have := name, spans[i].Name; want != have {
+			t.Errorf("unexpected span name; want %q, have %q", want, have)
+		}
+	}
+}
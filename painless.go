@@ -0,0 +1,47 @@
+package zipkines
+
+import (
+	"encoding/json"
+)
+
+// defaultPainlessContext is the context used by ES when none is given.
+const defaultPainlessContext = "painless_test"
+
+// parsePainlessExecute returns the script context of a painless execute
+// request body and the body to be tagged as query, which has the script
+// params redacted if requested.
+func parsePainlessExecute(body []byte, redact bool) (string, []byte) {
+	req := map[string]interface{}{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return "", nil
+	}
+
+	scriptContext := defaultPainlessContext
+	if c, ok := req["context"].(string); ok && c != "" {
+		scriptContext = c
+	}
+
+	if !redact {
+		return scriptContext, body
+	}
+
+	if script, ok := req["script"].(map[string]interface{}); ok {
+		if _, ok := script["params"]; ok {
+			script["params"] = "<redacted>"
+		}
+	}
+
+	query, err := json.Marshal(req)
+	if err != nil {
+		return scriptContext, nil
+	}
+	return scriptContext, query
+}
+
+// WithUnredactedPainlessParams tags the script params of the painless execute
+// calls as part of the query, which are otherwise redacted.
+func WithUnredactedPainlessParams() TraceOpt {
+	return func(r *Transport) {
+		r.opts.UnredactedPainlessParams = true
+	}
+}
@@ -0,0 +1,52 @@
+package zipkines
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestPainlessExecute(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	requestBody := `{"context":"score","script":{"source":"params.secret * 2","params":{"secret":42}}}`
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"result":"84"}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagQuery())
+	req, _ := http.NewRequest("POST", srv.URL+"/_scripts/painless/_execute", bytes.NewBufferString(requestBody))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "es/painless.execute", spans[0].Name; want != have {
+		t.Errorf("unexpected span name; want %q, have %q", want, have)
+	}
+
+	if want, have := "score", spans[0].Tags["es.painless.context"]; want != have {
+		t.Errorf("unexpected script context; want %q, have %q", want, have)
+	}
+
+	if want, have := "true", spans[0].Tags["es.painless.success"]; want != have {
+		t.Errorf("unexpected success; want %q, have %q", want, have)
+	}
+
+	if bytes.Contains([]byte(spans[0].Tags["es.query"]), []byte("42")) {
+		t.Errorf("expected script params to be redacted, have %q", spans[0].Tags["es.query"])
+	}
+}
@@ -0,0 +1,162 @@
+package zipkines
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// ResponsePointerRule tags the values found at the given JSON pointers in the
+// successful responses of an endpoint.
+type ResponsePointerRule struct {
+	// Endpoint is the path segment the rule applies to, e.g. "_stats".
+	Endpoint string
+	// Tags maps tag keys to JSON pointers.
+	Tags map[string]string
+}
+
+// matchingPointerRules returns the rules whose endpoint is part of the path.
+func matchingPointerRules(rules []ResponsePointerRule, path string) []ResponsePointerRule {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var matching []ResponsePointerRule
+	pieces := strings.Split(strings.Trim(path, "/"), "/")
+	for _, rule := range rules {
+		for _, piece := range pieces {
+			if piece == rule.Endpoint {
+				matching = append(matching, rule)
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// tagResponsePointers tags the values pointed by the rules in the decoded
+// documents of a body. Bodies made of several documents, e.g. NDJSON, are
+// pointed as an array of them. Values that can not be resolved are skipped.
+func tagResponsePointers(span zipkin.Span, docs []interface{}, rules []ResponsePointerRule) error {
+	var doc interface{} = docs
+	if len(docs) == 1 {
+		doc = docs[0]
+	}
+
+	switch doc.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return errors.New("the body is not a structured document")
+	}
+
+	for _, rule := range rules {
+		for key, pointer := range rule.Tags {
+			if val, ok := resolvePointer(doc, pointer); ok {
+				span.Tag(key, safeTagValue(val, 0))
+			}
+		}
+	}
+	return nil
+}
+
+// resolvePointer resolves a JSON pointer (RFC 6901) in a decoded document.
+// As an extension, a `*` token matches every member of an object or every
+// element of an array, in which case the numeric values found are summed.
+func resolvePointer(doc interface{}, pointer string) (string, bool) {
+	if pointer != "" && pointer[0] != '/' {
+		return "", false
+	}
+
+	var tokens []string
+	if pointer != "" {
+		tokens = strings.Split(pointer[1:], "/")
+	}
+
+	values := []interface{}{doc}
+	for _, token := range tokens {
+		token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+
+		var next []interface{}
+		for _, val := range values {
+			switch v := val.(type) {
+			case map[string]interface{}:
+				if token == "*" {
+					for _, m := range v {
+						next = append(next, m)
+					}
+				} else if m, ok := v[token]; ok {
+					next = append(next, m)
+				}
+			case []interface{}:
+				if token == "*" {
+					next = append(next, v...)
+				} else if i, err := strconv.Atoi(token); err == nil && i >= 0 && i < len(v) {
+					next = append(next, v[i])
+				}
+			}
+		}
+		values = next
+	}
+
+	switch len(values) {
+	case 0:
+		return "", false
+	case 1:
+		return formatPointerValue(values[0])
+	}
+
+	var sum int64
+	for _, val := range values {
+		n, ok := val.(json.Number)
+		if !ok {
+			return "", false
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return "", false
+		}
+		sum += i
+	}
+	return fmt.Sprintf("%d", sum), true
+}
+
+func formatPointerValue(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// WithResponsePointerTags tags the values found at the given JSON pointers in
+// the successful responses of the calls whose path includes the endpoint,
+// e.g. "_stats". The tags map the tag keys to the JSON pointers. A `*` token
+// in a pointer matches every member, in which case numeric values are summed.
+func WithResponsePointerTags(endpoint string, tags map[string]string) TraceOpt {
+	return func(r *Transport) {
+		r.opts.ResponsePointerRules = append(r.opts.ResponsePointerRules, ResponsePointerRule{Endpoint: endpoint, Tags: tags})
+	}
+}
+
+// WithTagIndexStats tags a handful of headline numbers from the `_stats` and
+// `_segments` responses: documents count, store size and segments count.
+func WithTagIndexStats() TraceOpt {
+	return func(r *Transport) {
+		WithResponsePointerTags("_stats", map[string]string{
+			"es.stats.docs.count":          "/_all/primaries/docs/count",
+			"es.stats.store.size_in_bytes": "/_all/primaries/store/size_in_bytes",
+			"es.stats.segments.count":      "/_all/primaries/segments/count",
+		})(r)
+		WithResponsePointerTags("_segments", map[string]string{
+			"es.segments.count": "/indices/*/shards/*/*/num_search_segments",
+		})(r)
+	}
+}
@@ -0,0 +1,89 @@
+package zipkines
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestResolvePointer(t *testing.T) {
+	doc := map[string]interface{}{
+		"a": map[string]interface{}{"b/c": "slash", "m~n": true},
+		"l": []interface{}{map[string]interface{}{"n": json.Number("2")}, map[string]interface{}{"n": json.Number("3")}},
+	}
+
+	testCases := []struct {
+		pointer  string
+		expected string
+		found    bool
+	}{
+		{"/a/b~1c", "slash", true},
+		{"/a/m~0n", "true", true},
+		{"/l/1/n", "3", true},
+		{"/l/*/n", "5", true},
+		{"/l/2/n", "", false},
+		{"/a", "", false},
+		{"a", "", false},
+	}
+
+	for _, tc := range testCases {
+		val, found := resolvePointer(doc, tc.pointer)
+		if want, have := tc.found, found; want != have {
+			t.Errorf("unexpected found for %q; want %t, have %t", tc.pointer, want, have)
+		}
+		if want, have := tc.expected, val; want != have {
+			t.Errorf("unexpected value for %q; want %q, have %q", tc.pointer, want, have)
+		}
+	}
+}
+
+func TestTagIndexStats(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/logs/_segments" {
+			rw.Write([]byte(`{"indices":{"logs":{"shards":{"0":[{"num_search_segments":4}],"1":[{"num_search_segments":3}]}}}}`))
+			return
+		}
+		rw.Write([]byte(`{"_all":{"primaries":{"docs":{"count":1200},"store":{"size_in_bytes":4096}}}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagIndexStats())
+
+	for _, path := range []string{"/logs/_stats", "/logs/_segments"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "1200", spans[0].Tags["es.stats.docs.count"]; want != have {
+		t.Errorf("unexpected docs count; want %q, have %q", want, have)
+	}
+
+	if want, have := "4096", spans[0].Tags["es.stats.store.size_in_bytes"]; want != have {
+		t.Errorf("unexpected store size; want %q, have %q", want, have)
+	}
+
+	if _, ok := spans[0].Tags["es.stats.segments.count"]; ok {
+		t.Errorf("unexpected segments count tag")
+	}
+
+	if want, have := "7", spans[1].Tags["es.segments.count"]; want != have {
+		t.Errorf("unexpected segments count; want %q, have %q", want, have)
+	}
+}
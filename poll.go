@@ -0,0 +1,94 @@
+package zipkines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// ErrInvalidPollInterval is returned when starting a poller with a non
+// positive interval.
+var ErrInvalidPollInterval = errors.New("the poll interval must be positive")
+
+// PollOpt configures the polls of the cluster made by the transport, e.g.
+// through StartClusterHealthPoller.
+type PollOpt func(p *poller)
+
+// WithPollRequestDecorator modifies the poll requests before they are sent,
+// e.g. to authenticate them:
+//
+//	WithPollRequestDecorator(func(req *http.Request) {
+//		req.SetBasicAuth("monitoring", password)
+//	})
+func WithPollRequestDecorator(decorate func(req *http.Request)) PollOpt {
+	return func(p *poller) {
+		p.decorate = decorate
+	}
+}
+
+type poller struct {
+	decorate func(req *http.Request)
+}
+
+// startPolling calls poll once before returning and then once every interval
+// until the context is cancelled.
+func startPolling(ctx context.Context, interval time.Duration, poll func()) error {
+	if interval <= 0 {
+		return ErrInvalidPollInterval
+	}
+
+	poll()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				poll()
+			}
+		}
+	}()
+	return nil
+}
+
+// fetch sends a GET request straight to the parent transport, hence it is
+// not traced, and returns no more than limit bytes of its body. Non
+// successful responses are returned as errors.
+func (p *poller) fetch(ctx context.Context, parent http.RoundTripper, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if p.decorate != nil {
+		p.decorate(req)
+	}
+
+	res, err := parent.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		io.Copy(ioutil.Discard, io.LimitReader(res.Body, limit))
+		return nil, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(res.Body, limit))
+}
+
+func newPoller(opts []PollOpt) *poller {
+	p := &poller{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
@@ -0,0 +1,136 @@
+package zipkines
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/propagation/b3"
+)
+
+// opaqueIDMode tells what is injected in the X-Opaque-Id header.
+type opaqueIDMode int
+
+const (
+	opaqueIDNone opaqueIDMode = iota
+	opaqueIDTrace
+	opaqueIDTraceAndSpan
+)
+
+// opaqueIDHeader is propagated by ES into its slow logs, the tasks API and
+// the deprecation logs.
+const opaqueIDHeader = "X-Opaque-Id"
+
+// traceparentHeader is the W3C trace context header recorded by the recent
+// ES versions.
+const traceparentHeader = "traceparent"
+
+// traceparent returns the W3C traceparent of a span context, the 64 bits
+// trace IDs are left padded with zeros.
+func traceparent(sc model.SpanContext) string {
+	flags := "00"
+	if sc.Debug || (sc.Sampled != nil && *sc.Sampled) {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%016x%016x-%016x-%s", sc.TraceID.High, sc.TraceID.Low, uint64(sc.ID), flags)
+}
+
+// injectHeaders returns a copy of the request carrying the propagation
+// headers of the span, or the request itself if there are none to inject.
+// The headers set by the caller are kept.
+func (r *Transport) injectHeaders(req *http.Request, sc model.SpanContext) *http.Request {
+	if r.opaqueID == opaqueIDNone && !r.traceContext && r.b3 == b3None {
+		return req
+	}
+
+	headers := map[string]string{}
+	if r.opaqueID != opaqueIDNone && req.Header.Get(opaqueIDHeader) == "" {
+		id := sc.TraceID.String()
+		if r.opaqueID == opaqueIDTraceAndSpan {
+			id += "-" + sc.ID.String()
+		}
+		headers[opaqueIDHeader] = id
+	}
+
+	if r.traceContext && req.Header.Get(traceparentHeader) == "" {
+		headers[traceparentHeader] = traceparent(sc)
+	}
+
+	// the B3 headers of the caller, if any, are kept altogether.
+	b3Inject := r.b3 != b3None && req.Header.Get(b3.Context) == "" && req.Header.Get(b3.TraceID) == ""
+	if len(headers) == 0 && !b3Inject {
+		return req
+	}
+
+	injected := new(http.Request)
+	*injected = *req
+	injected.Header = req.Header.Clone()
+	if injected.Header == nil {
+		injected.Header = http.Header{}
+	}
+	for key, val := range headers {
+		injected.Header.Set(key, val)
+	}
+	if b3Inject {
+		// the injection only fails on empty span contexts.
+		_ = b3.InjectHTTP(injected, r.b3.injectOptions()...)(sc)
+	}
+	return injected
+}
+
+// WithOpaqueIDPropagation injects the trace ID, followed by the span ID if
+// withSpanID is set, in the X-Opaque-Id header of the requests so the ES
+// slow logs and tasks can be joined with the traces, e.g.
+// "5af7183fb1d4cf5f-6b221d5bc9e6496c". The header is kept as is if the
+// caller already set it.
+func WithOpaqueIDPropagation(withSpanID bool) TraceOpt {
+	return func(r *Transport) {
+		r.opaqueID = opaqueIDTrace
+		if withSpanID {
+			r.opaqueID = opaqueIDTraceAndSpan
+		}
+	}
+}
+
+// WithTraceContextPropagation injects the W3C traceparent header derived from
+// the span context in the requests, so the ES versions recording it join the
+// traces. The header is kept as is if the caller already set it.
+func WithTraceContextPropagation() TraceOpt {
+	return func(r *Transport) {
+		r.traceContext = true
+	}
+}
+
+// B3Style is the style of the B3 propagation headers, see
+// WithB3Propagation.
+type B3Style int
+
+const (
+	b3None B3Style = iota
+	// B3Multi injects the "X-B3-*" headers.
+	B3Multi
+	// B3Single injects the single "b3" header.
+	B3Single
+	// B3SingleAndMulti injects both the single and the "X-B3-*" headers.
+	B3SingleAndMulti
+)
+
+func (s B3Style) injectOptions() []b3.InjectOption {
+	switch s {
+	case B3Single:
+		return []b3.InjectOption{b3.WithSingleHeaderOnly()}
+	case B3SingleAndMulti:
+		return []b3.InjectOption{b3.WithSingleAndMultiHeader()}
+	}
+	return nil
+}
+
+// WithB3Propagation injects the B3 headers of the span context in the given
+// style in the requests, so the spans of the proxies in front of ES, e.g. an
+// Envoy sidecar, join the traces. The headers are kept as they are if the
+// caller already set them.
+func WithB3Propagation(style B3Style) TraceOpt {
+	return func(r *Transport) {
+		r.b3 = style
+	}
+}
@@ -0,0 +1,149 @@
+package zipkines
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestOpaqueIDPropagation(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received = append(received, req.Header.Get("X-Opaque-Id"))
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		withSpanID bool
+		opaqueID   string
+	}{
+		{false, ""},
+		{true, ""},
+		{true, "batch-job"},
+	} {
+		transport := NewTransport(tracer, WithOpaqueIDPropagation(tc.withSpanID))
+		req, _ := http.NewRequest("GET", srv.URL+"/orders/_search", nil)
+		if tc.opaqueID != "" {
+			req.Header.Set("X-Opaque-Id", tc.opaqueID)
+		}
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+
+		if tc.opaqueID == "" && req.Header.Get("X-Opaque-Id") != "" {
+			t.Error("unexpected modification of the original request")
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 3, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expected := []string{
+		spans[0].TraceID.String(),
+		spans[1].TraceID.String() + "-" + spans[1].ID.String(),
+		"batch-job",
+	}
+	for i := range expected {
+		if want, have := expected[i], received[i]; want != have {
+			t.Errorf("unexpected X-Opaque-Id %d; want %q, have %q", i, want, have)
+		}
+	}
+}
+
+func TestTraceContextPropagation(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received = req.Header.Get("traceparent")
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTraceContextPropagation())
+	req, _ := http.NewRequest("GET", srv.URL+"/orders/_search", nil)
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expected := fmt.Sprintf("00-%016x%016x-%016x-01", spans[0].TraceID.High, spans[0].TraceID.Low, uint64(spans[0].ID))
+	if want, have := expected, received; want != have {
+		t.Errorf("unexpected traceparent; want %q, have %q", want, have)
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	sampled := false
+	sc := model.SpanContext{TraceID: model.TraceID{Low: 0xa3ce929d0e0e4736}, ID: 0xf67, Sampled: &sampled}
+	if want, have := "00-0000000000000000a3ce929d0e0e4736-0000000000000f67-00", traceparent(sc); want != have {
+		t.Errorf("unexpected traceparent; want %q, have %q", want, have)
+	}
+}
+
+func TestB3Propagation(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	var received []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received = append(received, req.Header)
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	styles := []B3Style{B3Multi, B3Single, B3SingleAndMulti}
+	for _, style := range styles {
+		transport := NewTransport(tracer, WithB3Propagation(style))
+		req, _ := http.NewRequest("GET", srv.URL+"/orders/_search", nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 3, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for i, style := range styles {
+		single := spans[i].TraceID.String() + "-" + spans[i].ID.String() + "-1"
+		multi := spans[i].TraceID.String()
+		if style == B3Single {
+			multi = ""
+		}
+		if style == B3Multi {
+			single = ""
+		}
+
+		if want, have := single, received[i].Get("b3"); want != have {
+			t.Errorf("unexpected b3 header for style %d; want %q, have %q", style, want, have)
+		}
+
+		if want, have := multi, received[i].Get("X-B3-TraceId"); want != have {
+			t.Errorf("unexpected X-B3-TraceId header for style %d; want %q, have %q", style, want, have)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package zipkines
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// maxQueryParamLength is the length above which the query param values are
+// hashed in the tagged query string.
+const maxQueryParamLength = 64
+
+// sanitizedQueryString returns the query string without the credentials
+// and with the opaque and oversized values replaced by a short hash plus
+// their length, the params are sorted by key.
+func sanitizedQueryString(rawQuery string, rawOpaque bool) string {
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// a query string which can not be parsed can not be sanitized
+		return ""
+	}
+
+	for key, vals := range params {
+		if isCredentialQueryParam(key) {
+			delete(params, key)
+			continue
+		}
+
+		for i, val := range vals {
+			if (opaqueQueryParams[key] && !rawOpaque) || len(val) > maxQueryParamLength {
+				vals[i] = fmt.Sprintf("%s (len %d)", shortHash(val), len(val))
+			}
+		}
+	}
+	return params.Encode()
+}
+
+// withoutCredentialParams returns the query string without the params which
+// might carry credentials, the params are sorted by key.
+func withoutCredentialParams(rawQuery string) string {
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// a query string which can not be parsed can not be filtered
+		return ""
+	}
+
+	for key := range params {
+		if isCredentialQueryParam(key) {
+			delete(params, key)
+		}
+	}
+	return params.Encode()
+}
+
+// WithTagRawQueryString tags the whole query string as "es.query_string"
+// rather than the whitelisted query params. The params which might carry
+// credentials, e.g. "api_key", are removed and the oversized values are
+// replaced by a short hash plus their length.
+func WithTagRawQueryString() TraceOpt {
+	return func(r *Transport) {
+		r.opts.TagRawQueryString = true
+	}
+}
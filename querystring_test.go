@@ -0,0 +1,59 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestSanitizedQueryString(t *testing.T) {
+	long := strings.Repeat("a", maxQueryParamLength+1)
+	testCases := []struct {
+		rawQuery string
+		expected string
+	}{
+		{"size=10&from=20", "from=20&size=10"},
+		{"size=10&api_key=abc&Password=def", "size=10"},
+		{"scroll_id=abc", "scroll_id=" + strings.Replace(shortHash("abc"), ":", "%3A", 1) + "+%28len+3%29"},
+		{"q=" + long, "q=" + strings.Replace(shortHash(long), ":", "%3A", 1) + "+%28len+65%29"},
+		{"q=%zz", ""},
+	}
+
+	for _, tc := range testCases {
+		if want, have := tc.expected, sanitizedQueryString(tc.rawQuery, false); want != have {
+			t.Errorf("unexpected query string for %q; want %q, have %q", tc.rawQuery, want, have)
+		}
+	}
+}
+
+func TestTagRawQueryString(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagRawQueryString(), WithMaxTagValueLength(16))
+	req, _ := http.NewRequest("GET", srv.URL+"/logs/_search?size=10&routing=user-1&token=secret", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "routing=user-1&s", spans[0].Tags["es.query_string"]; want != have {
+		t.Errorf("unexpected query string; want %q, have %q", want, have)
+	}
+}
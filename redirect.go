@@ -0,0 +1,116 @@
+package zipkines
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// maxDrainedRedirectBody is the maximum amount of bytes drained from a
+// redirect response body before closing it so the connection can be reused.
+const maxDrainedRedirectBody = 2 << 10
+
+func isRedirect(status int) bool {
+	switch status {
+	case 301, 302, 303, 307, 308:
+		return true
+	}
+	return false
+}
+
+// followRedirects follows up to the configured amount of redirects, the same
+// way http.Client does. The last response is returned as is when it is still
+// a redirect which can't be followed, e.g. because of a missing Location or
+// a request body which can't be replayed.
+func (r *Transport) followRedirects(req *http.Request, res *http.Response) (*http.Response, int, error) {
+	redirects := 0
+	for ; redirects < r.maxRedirects && isRedirect(res.StatusCode); redirects++ {
+		loc, err := res.Location()
+		if err != nil {
+			break
+		}
+
+		next, ok := redirectRequest(req, res.StatusCode, loc)
+		if !ok {
+			break
+		}
+
+		io.Copy(ioutil.Discard, io.LimitReader(res.Body, maxDrainedRedirectBody))
+		res.Body.Close()
+
+		res, err = r.parent.RoundTrip(next)
+		if err != nil {
+			return nil, redirects + 1, err
+		}
+		req = next
+	}
+	return res, redirects, nil
+}
+
+// redirectRequest builds the request following a redirect to the location.
+// It returns false if the request body can't be replayed.
+func redirectRequest(req *http.Request, status int, loc *url.URL) (*http.Request, bool) {
+	method := req.Method
+	var body io.ReadCloser
+	switch status {
+	case 301, 302, 303:
+		if method != "GET" && method != "HEAD" {
+			method = "GET"
+		}
+	case 307, 308:
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, false
+			}
+			var err error
+			if body, err = req.GetBody(); err != nil {
+				return nil, false
+			}
+		}
+	}
+
+	next, err := http.NewRequest(method, loc.String(), body)
+	if err != nil {
+		return nil, false
+	}
+	next = next.WithContext(req.Context())
+
+	if body != nil {
+		next.ContentLength = req.ContentLength
+		next.GetBody = req.GetBody
+	}
+
+	for key, vals := range req.Header {
+		next.Header[key] = vals
+	}
+
+	// as http.Client, credentials are not forwarded to other hosts
+	if !strings.EqualFold(hostWithoutPort(loc.Host), hostWithoutPort(req.URL.Host)) {
+		next.Header.Del("Authorization")
+		next.Header.Del("Cookie")
+	}
+
+	return next, true
+}
+
+// tagRedirect tags a redirect response which was handed back to the caller.
+func tagRedirect(span zipkin.Span, res *http.Response) {
+	span.Tag("es.redirected", "true")
+	if loc, err := res.Location(); err == nil {
+		span.Tag("es.redirect.location_host", loc.Host)
+	}
+}
+
+// WithFollowRedirects makes the transport follow up to max redirects, e.g.
+// returned by a proxy in front of the cluster. By default redirects are
+// handed back to the caller and tagged as such, without considering them
+// errors nor parsing their bodies.
+func WithFollowRedirects(max int) TraceOpt {
+	return func(r *Transport) {
+		r.maxRedirects = max
+	}
+}
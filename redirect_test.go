@@ -0,0 +1,101 @@
+package zipkines
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestRedirectIsTaggedAndNotParsed(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Location", "http://es-proxy.internal:9200/_search")
+		rw.WriteHeader(302)
+		rw.Write([]byte(`<html>moved</html>`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagErrorType(), WithTagTotalHits())
+	req, _ := http.NewRequest("GET", srv.URL+"/_search", nil)
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, have := 302, res.StatusCode; want != have {
+		t.Errorf("unexpected status code; want %d, have %d", want, have)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if _, ok := spans[0].Tags["error"]; ok {
+		t.Errorf("unexpected error tag")
+	}
+
+	if want, have := "true", spans[0].Tags["es.redirected"]; want != have {
+		t.Errorf("unexpected redirected tag; want %q, have %q", want, have)
+	}
+
+	if want, have := "es-proxy.internal:9200", spans[0].Tags["es.redirect.location_host"]; want != have {
+		t.Errorf("unexpected location host tag; want %q, have %q", want, have)
+	}
+}
+
+func TestFollowRedirects(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/old/_search" {
+			rw.Header().Set("Location", "/new/_search")
+			rw.WriteHeader(307)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		if want, have := `{"size":25}`, string(body); want != have {
+			t.Errorf("unexpected replayed body; want %q, have %q", want, have)
+		}
+		rw.Write([]byte(`{"hits":{"total":274}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithFollowRedirects(3), WithTagTotalHits())
+	req, _ := http.NewRequest("POST", srv.URL+"/old/_search", bytes.NewBufferString(`{"size":25}`))
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, have := 200, res.StatusCode; want != have {
+		t.Errorf("unexpected status code; want %d, have %d", want, have)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "1", spans[0].Tags["es.redirects"]; want != have {
+		t.Errorf("unexpected redirects tag; want %q, have %q", want, have)
+	}
+
+	if want, have := "274", spans[0].Tags["es.hits.total"]; want != have {
+		t.Errorf("unexpected hits tag; want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,55 @@
+package zipkines
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/openzipkin/zipkin-go/model"
+)
+
+// remoteEndpoint returns the remote endpoint of a request. The address is
+// the one of the URL host, if it is an IP, and the service name is the one
+// given through WithRemoteServiceName or the host name otherwise. Host
+// names are never resolved.
+func (r *Transport) remoteEndpoint(req *http.Request, tagHost bool) *model.Endpoint {
+	e := &model.Endpoint{ServiceName: r.remoteServiceName}
+	if e.ServiceName == "" {
+		if tagHost && req.Host != "" {
+			e.ServiceName = hostWithoutPort(req.Host)
+		} else {
+			e.ServiceName = req.URL.Hostname()
+		}
+	}
+
+	if ip := net.ParseIP(req.URL.Hostname()); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			e.IPv4 = ip4
+		} else {
+			e.IPv6 = ip
+		}
+	}
+
+	port := req.URL.Port()
+	if port == "" {
+		switch req.URL.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+	if p, err := strconv.ParseUint(port, 10, 16); err == nil {
+		e.Port = uint16(p)
+	}
+	return e
+}
+
+// WithRemoteServiceName sets the service name of the remote endpoint of the
+// spans, e.g. "elasticsearch", so the cluster shows up as a single service in
+// the Zipkin dependency diagram. By default the host name is used.
+func WithRemoteServiceName(name string) TraceOpt {
+	return func(r *Transport) {
+		r.remoteServiceName = name
+	}
+}
@@ -0,0 +1,83 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestRemoteEndpoint(t *testing.T) {
+	testCases := []struct {
+		url         string
+		serviceName string
+		ipv4        string
+		ipv6        string
+		port        uint16
+	}{
+		{"http://10.0.0.1:9200/_search", "10.0.0.1", "10.0.0.1", "", 9200},
+		{"http://[::1]:9200/_search", "::1", "", "::1", 9200},
+		{"https://es.internal/_search", "es.internal", "", "", 443},
+	}
+
+	transport := NewTransport(nil)
+	for _, tc := range testCases {
+		req, _ := http.NewRequest("GET", tc.url, nil)
+		e := transport.remoteEndpoint(req, false)
+
+		if want, have := tc.serviceName, e.ServiceName; want != have {
+			t.Errorf("unexpected service name for %s; want %q, have %q", tc.url, want, have)
+		}
+
+		if tc.ipv4 != "" && tc.ipv4 != e.IPv4.String() {
+			t.Errorf("unexpected IPv4 for %s; want %q, have %q", tc.url, tc.ipv4, e.IPv4)
+		}
+
+		if tc.ipv6 != "" && tc.ipv6 != e.IPv6.String() {
+			t.Errorf("unexpected IPv6 for %s; want %q, have %q", tc.url, tc.ipv6, e.IPv6)
+		}
+
+		if want, have := tc.port, e.Port; want != have {
+			t.Errorf("unexpected port for %s; want %d, have %d", tc.url, want, have)
+		}
+	}
+}
+
+func TestRemoteServiceName(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithRemoteServiceName("elasticsearch"), WithTagHost())
+	req, _ := http.NewRequest("GET", srv.URL+"/_cluster/health", nil)
+	req.Host = "cluster-a.es.internal:9200"
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if spans[0].RemoteEndpoint == nil {
+		t.Fatal("expected remote endpoint")
+	}
+
+	if want, have := "elasticsearch", spans[0].RemoteEndpoint.ServiceName; want != have {
+		t.Errorf("unexpected remote service name; want %q, have %q", want, have)
+	}
+
+	if want, have := "127.0.0.1", spans[0].RemoteEndpoint.IPv4.String(); want != have {
+		t.Errorf("unexpected remote IPv4; want %q, have %q", want, have)
+	}
+}
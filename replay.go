@@ -0,0 +1,60 @@
+package zipkines
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// ReplayRecord is a query sent to ES in a replayable form.
+type ReplayRecord struct {
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id"`
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	// Params holds the encoded query string of the request, without the
+	// params which might carry credentials, e.g. "api_key".
+	Params string `json:"params,omitempty"`
+	Index  string `json:"index,omitempty"`
+	Body   string `json:"body"`
+}
+
+// ReplaySink receives the replay records of the sampled queries. It is
+// called synchronously from the round trip, hence it should be fast and it
+// must be safe for concurrent use.
+type ReplaySink func(ReplayRecord)
+
+// NewReplayWriter returns a sink writing the records to w in NDJSON format,
+// one record per line. Writes are serialized. Write errors are ignored as
+// replay export must never fail a request.
+func NewReplayWriter(w io.Writer) ReplaySink {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(rec ReplayRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		enc.Encode(rec)
+	}
+}
+
+// NewReplayChannel returns a sink sending the records to ch. Records are
+// dropped when ch is not ready to receive them so requests are never
+// blocked.
+func NewReplayChannel(ch chan<- ReplayRecord) ReplaySink {
+	return func(rec ReplayRecord) {
+		select {
+		case ch <- rec:
+		default:
+		}
+	}
+}
+
+// WithQueryReplay exports the request bodies of the sampled spans, together
+// with their trace IDs and target index, to the sink, enabling load test
+// corpora to be built from production traces. Document IDs are redacted
+// according to the document ID policy.
+func WithQueryReplay(sink ReplaySink) TraceOpt {
+	return func(r *Transport) {
+		r.replay = sink
+	}
+}
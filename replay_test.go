@@ -0,0 +1,60 @@
+package zipkines
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestQueryReplay(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	out := &bytes.Buffer{}
+	transport := NewTransport(tracer, WithQueryReplay(NewReplayWriter(out)))
+
+	req, _ := http.NewRequest("GET", srv.URL+"/logs/_search?routing=1&api_key=c2VjcmV0&access_token=secret", bytes.NewBufferString(`{"size":25}`))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	rec := ReplayRecord{}
+	if err := json.Unmarshal(out.Bytes(), &rec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := ReplayRecord{
+		TraceID: spans[0].TraceID.String(),
+		SpanID:  spans[0].ID.String(),
+		Method:  "GET",
+		Path:    "/logs/_search",
+		Params:  "routing=1",
+		Index:   "logs",
+		Body:    `{"size":25}`,
+	}
+	if want, have := expected, rec; want != have {
+		t.Errorf("unexpected replay record; want %+v, have %+v", want, have)
+	}
+
+	if _, ok := spans[0].Tags["es.query"]; ok {
+		t.Errorf("unexpected query tag")
+	}
+}
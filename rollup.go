@@ -0,0 +1,86 @@
+package zipkines
+
+import (
+	"fmt"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// rollupAllIndices is the key used to aggregate calls which do not target
+// an index in their path, e.g. "/_search" or "/_cluster/health".
+const rollupAllIndices = "_all"
+
+type indexStats struct {
+	calls  int
+	errors int
+	total  time.Duration
+	max    time.Duration
+}
+
+// indexRollup keeps rolling per-index latency and error summaries for a
+// window of time. It records every call regardless of the sampling decision
+// of the span so it gives an overview even under aggressive sampling.
+type indexRollup struct {
+	*window
+}
+
+func newIndexRollup(interval time.Duration) *indexRollup {
+	return &indexRollup{newWindow(interval, func() interface{} {
+		return map[string]*indexStats{}
+	})}
+}
+
+// record adds a call to the current window. If the window is over it is
+// closed and returned so the caller can emit it outside of the lock.
+func (r *indexRollup) record(now time.Time, index string, d time.Duration, failed bool) (time.Time, map[string]*indexStats) {
+	if index == "" {
+		index = rollupAllIndices
+	}
+
+	start, closed := r.window.record(now, func(state interface{}) {
+		stats := state.(map[string]*indexStats)
+		s, ok := stats[index]
+		if !ok {
+			s = &indexStats{}
+			stats[index] = s
+		}
+		s.calls++
+		if failed {
+			s.errors++
+		}
+		s.total += d
+		if d > s.max {
+			s.max = d
+		}
+	})
+	if closed == nil {
+		return time.Time{}, nil
+	}
+	return start, closed.(map[string]*indexStats)
+}
+
+// emitRollup reports one local span per index covering the closed window.
+func (r *Transport) emitRollup(start, end time.Time, stats map[string]*indexStats) {
+	for index, s := range stats {
+		r.emitWindowSpan("es/rollup", start, end, func(span zipkin.Span) {
+			TagESIndex.Set(span, index)
+			span.Tag("es.rollup.calls", fmt.Sprintf("%d", s.calls))
+			span.Tag("es.rollup.errors", fmt.Sprintf("%d", s.errors))
+			span.Tag("es.rollup.latency.avg_ms", fmt.Sprintf("%d", (s.total/time.Duration(s.calls)).Milliseconds()))
+			span.Tag("es.rollup.latency.max_ms", fmt.Sprintf("%d", s.max.Milliseconds()))
+		})
+	}
+}
+
+// WithIndexRollup enables the aggregation of per-index latency and error
+// summaries which are emitted as local spans named "es/rollup" once every
+// interval. The first window starts with the first call and it is closed by
+// the first call made after the interval is over, hence an idle transport does
+// not emit rollups. A zero interval emits a rollup per call. The windows are
+// measured with the clock of WithClock, which also dates the rollup spans.
+func WithIndexRollup(interval time.Duration) TraceOpt {
+	return func(r *Transport) {
+		r.rollup = newIndexRollup(interval)
+	}
+}
@@ -0,0 +1,87 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestIndexRollupIsReportedWhenNotSampled(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.NeverSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(500)
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithIndexRollup(0))
+	req, err := http.NewRequest("GET", srv.URL+"/my-index/_search", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "es/rollup", spans[0].Name; want != have {
+		t.Errorf("unexpected span name; want %q, have %q", want, have)
+	}
+
+	if want, have := "my-index", spans[0].Tags["es.index"]; want != have {
+		t.Errorf("unexpected index; want %q, have %q", want, have)
+	}
+
+	if want, have := "1", spans[0].Tags["es.rollup.errors"]; want != have {
+		t.Errorf("unexpected errors count; want %q, have %q", want, have)
+	}
+}
+
+func TestIndexRollupWindowUsesClock(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.NeverSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	transport := NewTransport(tracer, WithIndexRollup(time.Minute), WithClock(clock))
+
+	for _, elapsed := range []time.Duration{0, 30 * time.Second, 31 * time.Second} {
+		now = now.Add(elapsed)
+		req, _ := http.NewRequest("GET", srv.URL+"/my-index/_search", nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "3", spans[0].Tags["es.rollup.calls"]; want != have {
+		t.Errorf("unexpected calls count; want %q, have %q", want, have)
+	}
+
+	if want, have := time.Minute+time.Second, spans[0].Duration; want != have {
+		t.Errorf("unexpected rollup duration; want %s, have %s", want, have)
+	}
+}
@@ -0,0 +1,75 @@
+package zipkines
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+type profileResponse struct {
+	Profile struct {
+		Shards []struct {
+			// ID has the form "[nodeId][index][shard]"
+			ID string `json:"id"`
+		} `json:"shards"`
+	} `json:"profile"`
+}
+
+// tagNodeHeaders tags the configured response headers exposing the node or
+// instance which served the request.
+func tagNodeHeaders(span zipkin.Span, headers []string, res *http.Response) {
+	for _, h := range headers {
+		if val := res.Header.Get(h); val != "" {
+			span.Tag("es.node."+strings.ToLower(h), val)
+		}
+	}
+}
+
+// tagProfileNodes tags the IDs of the nodes which served the shards of a
+// profiled search, i.e. one sent with `"profile": true`.
+func tagProfileNodes(span zipkin.Span, body []byte) error {
+	res := profileResponse{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	nodes := map[string]bool{}
+	for _, shard := range res.Profile.Shards {
+		if end := strings.Index(shard.ID, "]"); strings.HasPrefix(shard.ID, "[") && end > 1 {
+			nodes[shard.ID[1:end]] = true
+		}
+	}
+
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	span.Tag("es.profile.nodes", strings.Join(ids, ","))
+	return nil
+}
+
+// WithNodeHeaders tags the given response headers, e.g. the ones set by a
+// proxy or the cluster to expose the node or instance which served the
+// request, as "es.node.<header>" so hot replicas can be spotted from the
+// client side.
+func WithNodeHeaders(headers ...string) TraceOpt {
+	return func(r *Transport) {
+		r.opts.NodeHeaders = headers
+	}
+}
+
+// WithTagProfileNodes tags the IDs of the nodes which served the shards of
+// the profiled searches.
+func WithTagProfileNodes() TraceOpt {
+	return func(r *Transport) {
+		r.opts.TagProfileNodes = true
+	}
+}
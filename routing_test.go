@@ -0,0 +1,43 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestNodeSelectionTags(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-Found-Handling-Instance", "instance-0000000003")
+		rw.Write([]byte(`{"profile":{"shards":[{"id":"[nodeB][logs][0]"},{"id":"[nodeA][logs][1]"},{"id":"[nodeB][logs][2]"}]}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithNodeHeaders("X-Found-Handling-Instance"), WithTagProfileNodes())
+	req, _ := http.NewRequest("POST", srv.URL+"/logs/_search", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "instance-0000000003", spans[0].Tags["es.node.x-found-handling-instance"]; want != have {
+		t.Errorf("unexpected node header tag; want %q, have %q", want, have)
+	}
+
+	if want, have := "nodeA,nodeB", spans[0].Tags["es.profile.nodes"]; want != have {
+		t.Errorf("unexpected profile nodes tag; want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,28 @@
+package zipkines
+
+import (
+	"fmt"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// tagSampled tags whether the span will be reported and logs the spans which
+// will not.
+func (r *Transport) tagSampled(span zipkin.Span) {
+	sampled := isSampled(span)
+	span.Tag("es.sampled.local_decision", fmt.Sprintf("%t", sampled))
+	if !sampled {
+		r.logger.Print(spanLogPrefix(span) + "span not sampled, it will not be reported")
+	}
+}
+
+// WithTagSamplingDecision tags the spans with "es.sampled.local_decision",
+// which tells whether they will be reported, e.g. for the reporters or span
+// recorders of tracers not using noop spans, and logs the spans which will
+// not be reported along with their IDs. It is meant to diagnose missing
+// spans as it logs on every unsampled request.
+func WithTagSamplingDecision() TraceOpt {
+	return func(r *Transport) {
+		r.tagSamplingDecision = true
+	}
+}
@@ -0,0 +1,51 @@
+package zipkines
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestTagSamplingDecision(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	for _, sampled := range []bool{true, false} {
+		reporter := recorder.NewReporter()
+		tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(func(uint64) bool { return sampled }))
+
+		logs := &bytes.Buffer{}
+		transport := NewTransport(
+			tracer,
+			WithTagSamplingDecision(),
+			WithLogger(log.New(logs, "", 0)),
+		)
+		req, _ := http.NewRequest("GET", srv.URL+"/logs/_search", nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		spans := reporter.Flush()
+		if sampled {
+			if want, have := 1, len(spans); want != have {
+				t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+			}
+
+			if want, have := "true", spans[0].Tags["es.sampled.local_decision"]; want != have {
+				t.Errorf("unexpected sampling decision; want %q, have %q", want, have)
+			}
+		}
+
+		if want, have := !sampled, strings.Contains(logs.String(), "not sampled"); want != have {
+			t.Errorf("unexpected log for sampled %t: %q", sampled, logs.String())
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package zipkines
+
+// QuerySanitizer returns the value to be tagged as the query of a request,
+// with false to leave the query untagged. The body has the document IDs
+// already treated according to the document ID policy.
+type QuerySanitizer func(method, path string, body []byte) (string, bool)
+
+// queryTagValue returns the value to be tagged as "es.query" for a request
+// body, if any.
+func (r *Transport) queryTagValue(opts TraceOpts, method, path string, body []byte) (string, bool) {
+	body = opts.DocIDPolicy.redactBody(body)
+	query := string(body)
+	if r.querySanitizer != nil {
+		var ok bool
+		if query, ok = r.querySanitizer(method, path, body); !ok {
+			return "", false
+		}
+	}
+	return safeTagValue(query, opts.MaxTagValueLength), true
+}
+
+// WithQuerySanitizer passes the queries through the sanitizer before they are
+// tagged by WithTagQuery, so they can be redacted, e.g. the PII in the term
+// filters, truncated or dropped per request. The sanitized value is still
+// made safe and capped as any other tag value. When used with
+// Instrumentation the method is empty and the path is the endpoint name,
+// e.g. "search".
+func WithQuerySanitizer(s QuerySanitizer) TraceOpt {
+	return func(r *Transport) {
+		r.querySanitizer = s
+	}
+}
@@ -0,0 +1,49 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestQuerySanitizer(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	sanitizer := func(method, path string, body []byte) (string, bool) {
+		if strings.HasPrefix(path, "/users") {
+			return "", false
+		}
+		return strings.Replace(string(body), "jane@example.com", "?", -1), true
+	}
+
+	transport := NewTransport(tracer, WithTagQuery(), WithQuerySanitizer(sanitizer))
+	for _, path := range []string{"/logs/_search", "/users/_search"} {
+		req, _ := http.NewRequest("POST", srv.URL+path, strings.NewReader(`{"query":{"term":{"email":"jane@example.com"}}}`))
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := `{"query":{"term":{"email":"?"}}}`, spans[0].Tags["es.query"]; want != have {
+		t.Errorf("unexpected query; want %q, have %q", want, have)
+	}
+
+	if _, ok := spans[1].Tags["es.query"]; ok {
+		t.Errorf("expected the dropped query not to be tagged")
+	}
+}
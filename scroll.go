@@ -0,0 +1,88 @@
+package zipkines
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// scrollID returns the scroll ID sent by a scroll request, either in the
+// path, the query string or the body.
+func scrollID(req *http.Request, body []byte) string {
+	if pieces := splitPath(req.URL.Path); len(pieces) == 3 {
+		return pieces[2]
+	}
+
+	if id := req.URL.Query().Get("scroll_id"); id != "" {
+		return id
+	}
+
+	b := struct {
+		ScrollID json.RawMessage `json:"scroll_id"`
+	}{}
+	if len(body) == 0 || json.Unmarshal(body, &b) != nil {
+		return ""
+	}
+
+	var id string
+	if json.Unmarshal(b.ScrollID, &id) == nil {
+		return id
+	}
+	// clear scroll accepts a list of IDs, the first one correlates
+	var ids []string
+	if json.Unmarshal(b.ScrollID, &ids) == nil && len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+// tagScrollID tags a hash of the scroll ID, which is huge and opaque, so the
+// search opening a scroll and its pages can be correlated.
+func tagScrollID(span zipkin.Span, id string) {
+	if id != "" {
+		span.Tag("es.scroll_id", fmt.Sprintf("%s (len %d)", shortHash(id), len(id)))
+	}
+}
+
+// tagOpenedScrollID tags the scroll ID returned by a search opening a scroll.
+func tagOpenedScrollID(span zipkin.Span, body []byte) {
+	res := struct {
+		ScrollID string `json:"_scroll_id"`
+	}{}
+	if json.Unmarshal(body, &res) == nil {
+		tagScrollID(span, res.ScrollID)
+	}
+}
+
+type scrollSessionKey struct{}
+
+type scrollSession struct {
+	pages int64
+}
+
+// countScrollPage counts a scroll request made within a scroll session.
+func countScrollPage(ctx context.Context) {
+	if s, ok := ctx.Value(scrollSessionKey{}).(*scrollSession); ok {
+		atomic.AddInt64(&s.pages, 1)
+	}
+}
+
+// StartScrollSession starts a local span named "es/scroll_session", child of
+// the span in the context if any, to be the parent of the search opening a
+// scroll and of all its pages when they are requested with the returned
+// context, so the whole pagination shows up as one tree. The returned
+// function finishes the span, tagged with the number of scroll requests made
+// as "es.scroll.pages".
+func (r *Transport) StartScrollSession(ctx context.Context) (context.Context, func()) {
+	span, ctx := r.tracer.StartSpanFromContext(ctx, r.spanName("es/scroll_session"))
+	session := &scrollSession{}
+	ctx = context.WithValue(ctx, scrollSessionKey{}, session)
+	return ctx, func() {
+		span.Tag("es.scroll.pages", fmt.Sprintf("%d", atomic.LoadInt64(&session.pages)))
+		span.Finish()
+	}
+}
@@ -0,0 +1,80 @@
+package zipkines
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestScrollIsCorrelated(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	scrollID := "DXF1ZXJ5QW5kRmV0Y2gBAAAAAAAAAD4WYm9laVYtZndUQlNsdDcwakFMNjU1QQ=="
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(rw, `{"_scroll_id":%q,"hits":{"total":2,"hits":[]}}`, scrollID)
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+	ctx, finish := transport.StartScrollSession(context.Background())
+
+	requests := []struct {
+		method, path, body string
+	}{
+		{"POST", "/logs/_search?scroll=1m", `{"size":100}`},
+		{"POST", "/_search/scroll", `{"scroll":"1m","scroll_id":"` + scrollID + `"}`},
+		{"GET", "/_search/scroll/" + scrollID, ""},
+		{"DELETE", "/_search/scroll", `{"scroll_id":["` + scrollID + `"]}`},
+	}
+	for _, r := range requests {
+		req, _ := http.NewRequest(r.method, srv.URL+r.path, strings.NewReader(r.body))
+		res, err := transport.RoundTrip(req.WithContext(ctx))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+	finish()
+
+	spans := reporter.Flush()
+	if want, have := 5, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	session := spans[4]
+	if want, have := "es/scroll_session", session.Name; want != have {
+		t.Fatalf("unexpected session span name; want %q, have %q", want, have)
+	}
+
+	if want, have := "2", session.Tags["es.scroll.pages"]; want != have {
+		t.Errorf("unexpected scroll pages; want %q, have %q", want, have)
+	}
+
+	expectedID := fmt.Sprintf("%s (len %d)", shortHash(scrollID), len(scrollID))
+	expectedNames := []string{"es/_search", "es/scroll", "es/scroll", "es/clear_scroll"}
+	for i, span := range spans[:4] {
+		if want, have := expectedNames[i], span.Name; want != have {
+			t.Errorf("unexpected span name %d; want %q, have %q", i, want, have)
+		}
+
+		if want, have := expectedID, span.Tags["es.scroll_id"]; want != have {
+			t.Errorf("unexpected scroll ID for %q; want %q, have %q", span.Name, want, have)
+		}
+
+		if span.ParentID == nil || *span.ParentID != session.ID {
+			t.Errorf("expected %q to be a child of the scroll session", span.Name)
+		}
+	}
+}
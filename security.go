@@ -0,0 +1,26 @@
+package zipkines
+
+import "strings"
+
+// isSecurityPath tells whether the path addresses the security APIs of ES,
+// including the legacy X-Pack ones, or the ones of the OpenSearch security
+// plugin. Their request and response bodies carry passwords and API keys so
+// they are never tagged, whatever the tracing options.
+func isSecurityPath(path string) bool {
+	pieces := splitPath(path)
+	switch {
+	case len(pieces) >= 1 && pieces[0] == "_security":
+		return true
+	case len(pieces) >= 2 && pieces[0] == "_xpack" && pieces[1] == "security":
+		return true
+	case len(pieces) >= 2 && (pieces[0] == "_plugins" || pieces[0] == "_opendistro") && pieces[1] == "_security":
+		return true
+	}
+	return false
+}
+
+// isSecurityEndpoint tells whether the endpoint name reported by the official
+// client, e.g. "security.create_api_key", belongs to the security APIs.
+func isSecurityEndpoint(endpoint string) bool {
+	return strings.HasPrefix(endpoint, "security.")
+}
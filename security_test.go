@@ -0,0 +1,66 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestIsSecurityPath(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"/_security/user/alice/_password":        true,
+		"/_security/api_key":                     true,
+		"/_xpack/security/user/alice":            true,
+		"/_plugins/_security/api/internalusers/": true,
+		"/_opendistro/_security/authinfo":        true,
+		"/security/_search":                      false,
+		"/_xpack":                                false,
+		"/":                                      false,
+	} {
+		if want, have := expected, isSecurityPath(path); want != have {
+			t.Errorf("unexpected security detection for %q; want %t, have %t", path, want, have)
+		}
+	}
+}
+
+func TestSecurityBodiesAreNeverTagged(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(`{"type":"invalid password s3cr3t"}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagQuery(), WithTagErrorType())
+	for _, path := range []string{"/_security/user/alice/_password", "/_xpack/security/user/alice/_password"} {
+		req, _ := http.NewRequest("POST", srv.URL+path, strings.NewReader(`{"password":"s3cr3t"}`))
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for _, span := range spans {
+		for key, val := range span.Tags {
+			if strings.Contains(val, "s3cr3t") {
+				t.Errorf("unexpected password in %q tag of %q", key, span.Name)
+			}
+		}
+
+		if want, have := "400", span.Tags["error"]; want != have {
+			t.Errorf("unexpected error; want %q, have %q", want, have)
+		}
+	}
+}
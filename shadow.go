@@ -0,0 +1,142 @@
+package zipkines
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+type shadowKey struct{}
+
+func isShadowFromContext(ctx context.Context) bool {
+	shadow, _ := ctx.Value(shadowKey{}).(bool)
+	return shadow
+}
+
+// defaultShadowTimeout bounds the shadow requests of ShadowRead unless
+// WithShadowTimeout says otherwise.
+const defaultShadowTimeout = 10 * time.Second
+
+// ShadowRead sends the same search body to a primary and a shadow URL, e.g.
+// "http://es-old:9200/logs/_search" and "http://es-new:9200/logs/_search",
+// to compare them during a migration. Both calls are traced as siblings
+// under a local "es/shadow_read" span, the shadow one tagged with
+// "es.shadow=true", and the local span is tagged with a diff summary: the
+// total hits of both responses, their delta and the took delta in
+// milliseconds (shadow minus primary). The primary response is returned
+// with its body unread as soon as it is received: the shadow request runs
+// in the background, bounded by the shadow timeout instead of the context
+// of the caller, and the responses are compared once the primary body is
+// read or closed, so the lazily parsed values are compared too. The shadow
+// response is discarded and a shadow failure is tagged but not returned.
+func (r *Transport) ShadowRead(ctx context.Context, primaryURL, shadowURL string, body []byte) (*http.Response, error) {
+	span, ctx := r.tracer.StartSpanFromContext(ctx, r.spanName("es/shadow_read"))
+
+	primaryCtx := ContextWithResponseMeta(ctx)
+	res, err := r.search(primaryCtx, primaryURL, body)
+	if err != nil {
+		zipkin.TagError.Set(span, err.Error())
+		span.Finish()
+		return nil, err
+	}
+
+	// the response is owned by the caller once returned.
+	statusCode := res.StatusCode
+	primaryRead := make(chan struct{})
+	if r.lazyResponseParsing && res.Body != nil && res.Body != http.NoBody {
+		res.Body = &signalingBody{ReadCloser: res.Body, done: primaryRead}
+	} else {
+		close(primaryRead)
+	}
+
+	shadowCtx, cancel := context.WithTimeout(detachedContext{ctx}, r.shadowTimeout)
+	shadowCtx = context.WithValue(ContextWithResponseMeta(shadowCtx), shadowKey{}, true)
+	go func() {
+		defer span.Finish()
+		defer cancel()
+
+		shadowRes, err := r.search(shadowCtx, shadowURL, body)
+		if err != nil {
+			span.Tag("es.shadow.error", err.Error())
+			return
+		}
+		io.Copy(ioutil.Discard, shadowRes.Body)
+		shadowRes.Body.Close()
+
+		<-primaryRead
+		if statusCode != shadowRes.StatusCode {
+			span.Tag("es.shadow.status_code.primary", fmt.Sprintf("%d", statusCode))
+			span.Tag("es.shadow.status_code.shadow", fmt.Sprintf("%d", shadowRes.StatusCode))
+		}
+
+		primary, shadow := ResponseMetaFromContext(primaryCtx), ResponseMetaFromContext(shadowCtx)
+		span.Tag("es.shadow.hits.primary", fmt.Sprintf("%d", primary.HitsTotal))
+		span.Tag("es.shadow.hits.shadow", fmt.Sprintf("%d", shadow.HitsTotal))
+		span.Tag("es.shadow.hits.delta", fmt.Sprintf("%d", shadow.HitsTotal-primary.HitsTotal))
+		span.Tag("es.shadow.took.delta_ms", fmt.Sprintf("%d", shadow.Took-primary.Took))
+	}()
+
+	return res, nil
+}
+
+// WithShadowTimeout bounds the shadow requests sent by ShadowRead, which
+// outlive the context of the caller. Defaults to 10 seconds.
+func WithShadowTimeout(d time.Duration) TraceOpt {
+	return func(r *Transport) {
+		r.shadowTimeout = d
+	}
+}
+
+// detachedContext keeps the values of a context, e.g. the parent span, but
+// not its cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// signalingBody closes done once the body is read to the end or closed, for
+// its lazily parsed values to be available.
+type signalingBody struct {
+	io.ReadCloser
+	once sync.Once
+	done chan struct{}
+}
+
+func (b *signalingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.signal()
+	}
+	return n, err
+}
+
+func (b *signalingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.signal()
+	return err
+}
+
+func (b *signalingBody) signal() {
+	b.once.Do(func() {
+		close(b.done)
+	})
+}
+
+func (r *Transport) search(ctx context.Context, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return r.RoundTrip(req.WithContext(ctx))
+}
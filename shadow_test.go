@@ -0,0 +1,186 @@
+package zipkines
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+// notifyingReporter records the spans and notifies each of them, for the
+// spans finished in the background to be waited for.
+type notifyingReporter struct {
+	*recorder.ReporterRecorder
+	sent chan struct{}
+}
+
+func newNotifyingReporter() *notifyingReporter {
+	return &notifyingReporter{ReporterRecorder: recorder.NewReporter(), sent: make(chan struct{}, 16)}
+}
+
+func (r *notifyingReporter) Send(span model.SpanModel) {
+	r.ReporterRecorder.Send(span)
+	r.sent <- struct{}{}
+}
+
+func (r *notifyingReporter) wait(t *testing.T, n int) []model.SpanModel {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.sent:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the spans; want %d, have %d", n, i)
+		}
+	}
+	return r.Flush()
+}
+
+func TestShadowRead(t *testing.T) {
+	reporter := newNotifyingReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	primary := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"took":12,"hits":{"total":274}}`))
+	}))
+	defer primary.Close()
+
+	shadow := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"took":5,"hits":{"total":270}}`))
+	}))
+	defer shadow.Close()
+
+	transport := NewTransport(tracer)
+	res, err := transport.ShadowRead(context.Background(), primary.URL+"/logs/_search", shadow.URL+"/logs/_search", []byte(`{"query":{"match_all":{}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if want, have := `{"took":12,"hits":{"total":274}}`, string(body); want != have {
+		t.Errorf("unexpected primary body; want %q, have %q", want, have)
+	}
+
+	spans := reporter.wait(t, 3)
+	if want, have := 3, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	primarySpan, shadowSpan, local := spans[0], spans[1], spans[2]
+	if want, have := "", primarySpan.Tags["es.shadow"]; want != have {
+		t.Errorf("unexpected shadow tag on the primary span; want %q, have %q", want, have)
+	}
+
+	if want, have := "true", shadowSpan.Tags["es.shadow"]; want != have {
+		t.Errorf("unexpected shadow tag on the shadow span; want %q, have %q", want, have)
+	}
+
+	if want, have := local.ID, *shadowSpan.ParentID; want != have {
+		t.Errorf("unexpected shadow span parent; want %s, have %s", want, have)
+	}
+
+	if want, have := *primarySpan.ParentID, *shadowSpan.ParentID; want != have {
+		t.Errorf("unexpected non sibling spans; want parent %s, have %s", want, have)
+	}
+
+	expectedTags := map[string]string{
+		"es.shadow.hits.primary":  "274",
+		"es.shadow.hits.shadow":   "270",
+		"es.shadow.hits.delta":    "-4",
+		"es.shadow.took.delta_ms": "-7",
+	}
+	for key, val := range expectedTags {
+		if want, have := val, local.Tags[key]; want != have {
+			t.Errorf("unexpected %q tag; want %q, have %q", key, want, have)
+		}
+	}
+}
+
+func TestShadowReadComparesLazilyParsedResponses(t *testing.T) {
+	reporter := newNotifyingReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	primary := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"took":12,"hits":{"total":274}}`))
+	}))
+	defer primary.Close()
+
+	shadow := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"took":5,"hits":{"total":270}}`))
+	}))
+	defer shadow.Close()
+
+	transport := NewTransport(tracer, WithLazyResponseParsing())
+	res, err := transport.ShadowRead(context.Background(), primary.URL+"/logs/_search", shadow.URL+"/logs/_search", []byte(`{"query":{"match_all":{}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	spans := reporter.wait(t, 3)
+	local := spans[2]
+	if want, have := "274", local.Tags["es.shadow.hits.primary"]; want != have {
+		t.Errorf("unexpected primary hits; want %q, have %q", want, have)
+	}
+
+	if want, have := "-4", local.Tags["es.shadow.hits.delta"]; want != have {
+		t.Errorf("unexpected hits delta; want %q, have %q", want, have)
+	}
+}
+
+func TestShadowReadDoesNotWaitForTheShadow(t *testing.T) {
+	reporter := newNotifyingReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	primary := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"took":12,"hits":{"total":274}}`))
+	}))
+	defer primary.Close()
+
+	release := make(chan struct{})
+	shadow := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		<-release
+	}))
+	defer shadow.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	transport := NewTransport(tracer, WithShadowTimeout(50*time.Millisecond))
+	res, err := transport.ShadowRead(ctx, primary.URL+"/logs/_search", shadow.URL+"/logs/_search", []byte(`{"query":{"match_all":{}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the cancellation of the caller context does not cancel the shadow.
+	cancel()
+	res.Body.Close()
+
+	spans := reporter.wait(t, 3)
+	local := spans[2]
+	if want, have := "es/shadow_read", local.Name; want != have {
+		t.Fatalf("unexpected span name; want %q, have %q", want, have)
+	}
+
+	if !strings.Contains(local.Tags["es.shadow.error"], "deadline exceeded") {
+		t.Errorf("unexpected shadow error; want a timeout, have %q", local.Tags["es.shadow.error"])
+	}
+}
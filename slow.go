@@ -0,0 +1,50 @@
+package zipkines
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+type tookResponse struct {
+	Took *int64 `json:"took"`
+}
+
+// tagTook tags the took of a response, if any.
+func tagTook(span zipkin.Span, body []byte) {
+	res := tookResponse{}
+	if err := json.Unmarshal(body, &res); err == nil && res.Took != nil {
+		span.Tag("es.took_ms", fmt.Sprintf("%d", *res.Took))
+	}
+}
+
+// checkServerSlow annotates the span and logs, along with the trace ID, the
+// responses whose ES reported took exceeds the threshold.
+func (r *Transport) checkServerSlow(span zipkin.Span, body []byte) {
+	res := tookResponse{}
+	if err := json.Unmarshal(body, &res); err != nil || res.Took == nil {
+		return
+	}
+
+	took := time.Duration(*res.Took) * time.Millisecond
+	if took <= r.serverSlowThreshold {
+		return
+	}
+
+	span.Tag("es.server_slow", "true")
+	span.Annotate(time.Now(), "es.server_slow")
+	r.logger.Print(spanLogPrefix(span) + fmt.Sprintf("slow ES response: took %s, over the %s threshold", took, r.serverSlowThreshold))
+}
+
+// WithServerSlowThreshold tags as "es.server_slow", annotates and logs with
+// the trace ID the responses whose took, the time ES reports it spent
+// processing the request, exceeds the threshold. It catches the cluster
+// side slowness hidden by generous client timeouts, regardless of the
+// sampling decision.
+func WithServerSlowThreshold(d time.Duration) TraceOpt {
+	return func(r *Transport) {
+		r.serverSlowThreshold = d
+	}
+}
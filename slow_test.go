@@ -0,0 +1,105 @@
+package zipkines
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestServerSlowThreshold(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/slow/_search" {
+			rw.Write([]byte(`{"took":1500}`))
+			return
+		}
+		rw.Write([]byte(`{"took":20}`))
+	}))
+	defer srv.Close()
+
+	logs := &bytes.Buffer{}
+	// the clock does not affect the timestamps of the spans.
+	clock := func() time.Time { return time.Unix(0, 0) }
+	transport := NewTransport(tracer, WithLogger(log.New(logs, "", 0)), WithServerSlowThreshold(time.Second), WithClock(clock))
+
+	for _, path := range []string{"/slow/_search", "/fast/_search"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "true", spans[0].Tags["es.server_slow"]; want != have {
+		t.Errorf("unexpected slow tag; want %q, have %q", want, have)
+	}
+
+	if want, have := 1, len(spans[0].Annotations); want != have {
+		t.Fatalf("unexpected annotations number; want %d, have %d", want, have)
+	}
+
+	if ts := spans[0].Annotations[0].Timestamp; ts.Before(spans[0].Timestamp) {
+		t.Errorf("unexpected annotation timestamp %s before the span start %s", ts, spans[0].Timestamp)
+	}
+
+	if want, have := "", spans[1].Tags["es.server_slow"]; want != have {
+		t.Errorf("unexpected slow tag; want %q, have %q", want, have)
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if want, have := 1, len(lines); want != have {
+		t.Fatalf("unexpected log lines number; want %d, have %d: %q", want, have, logs.String())
+	}
+
+	if !strings.Contains(lines[0], "trace_id="+spans[0].TraceID.String()) {
+		t.Errorf("expected the trace ID in the log line, have %q", lines[0])
+	}
+}
+
+func TestTagTook(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/_bulk" {
+			rw.Write([]byte(`{"took":30,"errors":false,"items":[]}`))
+			return
+		}
+		rw.Write([]byte(`{"acknowledged":true}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagTook())
+	for _, path := range []string{"/_bulk", "/logs"} {
+		req, _ := http.NewRequest("PUT", srv.URL+path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "30", spans[0].Tags["es.took_ms"]; want != have {
+		t.Errorf("unexpected took; want %q, have %q", want, have)
+	}
+
+	if _, ok := spans[1].Tags["es.took_ms"]; ok {
+		t.Error("expected no took for a response without it")
+	}
+}
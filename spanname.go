@@ -0,0 +1,177 @@
+package zipkines
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// SpanNamePolicy caps and sanitizes the span names derived from the requests,
+// e.g. from their paths or target indices, to keep pathological requests from
+// producing huge or high cardinality names.
+type SpanNamePolicy struct {
+	// MaxLength is the maximum length in bytes of the span names, longer
+	// ones are cut at a rune boundary. A non positive value removes the
+	// limit.
+	MaxLength int
+	// Sanitize rewrites the span names before they are capped, none is
+	// applied if nil.
+	Sanitize func(name string) string
+}
+
+// DefaultSpanNamePolicy caps the span names to 128 bytes after sanitizing them
+// with SanitizeSpanName.
+var DefaultSpanNamePolicy = SpanNamePolicy{MaxLength: 128, Sanitize: SanitizeSpanName}
+
+func (p SpanNamePolicy) apply(name string) string {
+	if p.Sanitize != nil {
+		name = p.Sanitize(name)
+	}
+
+	if p.MaxLength > 0 && len(name) > p.MaxLength {
+		cut := p.MaxLength
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = name[:cut]
+	}
+	return name
+}
+
+// SanitizeSpanName drops the query string from a span name and replaces the
+// pieces looking like IDs, e.g. numbers, UUIDs or base64 strings, with
+// "{id}". Index names are kept as ES only allows lowercase ones.
+func SanitizeSpanName(name string) string {
+	if i := strings.IndexByte(name, '?'); i >= 0 {
+		name = name[:i]
+	}
+
+	var b strings.Builder
+	start := 0
+	for i := 0; i <= len(name); i++ {
+		if i < len(name) && !isSpanNameSeparator(name[i]) {
+			continue
+		}
+		if piece := name[start:i]; looksLikeID(piece) {
+			b.WriteString("{id}")
+		} else {
+			b.WriteString(piece)
+		}
+		if i < len(name) {
+			b.WriteByte(name[i])
+		}
+		start = i + 1
+	}
+	return b.String()
+}
+
+func isSpanNameSeparator(c byte) bool {
+	return c == ' ' || c == '/' || c == ','
+}
+
+// looksLikeID tells whether a piece of a span name is likely an ID rather
+// than a name.
+func looksLikeID(piece string) bool {
+	var digits, hex, upper, lower int
+	for _, c := range piece {
+		switch {
+		case unicode.IsDigit(c):
+			digits++
+			hex++
+		case c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F':
+			hex++
+		}
+		if unicode.IsUpper(c) {
+			upper++
+		} else if unicode.IsLower(c) {
+			lower++
+		}
+	}
+
+	dashes := strings.Count(piece, "-")
+	switch {
+	case digits >= 6 && digits == len(piece):
+		// numeric IDs
+		return true
+	case len(piece) >= 16 && digits > 0 && hex+dashes == len(piece) && upper == 0:
+		// UUIDs and hashes
+		return true
+	case len(piece) >= 16 && digits > 0 && upper > 0 && lower > 0:
+		// base64 and the like, e.g. the ES generated document IDs
+		return true
+	}
+	return false
+}
+
+// nameRecorder applies the span name policy to the names of a span and keeps
+// track of the name as it is refined along the request handling.
+type nameRecorder struct {
+	zipkin.Span
+	name   string
+	policy SpanNamePolicy
+	prefix string
+}
+
+func (s *nameRecorder) SetName(name string) {
+	name = withOperationPrefix(name, s.prefix)
+	name = s.policy.apply(name)
+	s.Span.SetName(name)
+	s.name = name
+}
+
+// WithIndexInSpanName appends the index expression targeted by a request to
+// the span name, e.g. "es/_search my-index" or "es/search logs-*,metrics".
+// Beware wildcards and date math might make the span names cardinality grow.
+func WithIndexInSpanName() TraceOpt {
+	return func(r *Transport) {
+		r.indexInSpanName = true
+	}
+}
+
+// WithSpanNamePolicy replaces DefaultSpanNamePolicy as the policy capping and
+// sanitizing the span names.
+func WithSpanNamePolicy(p SpanNamePolicy) TraceOpt {
+	return func(r *Transport) {
+		r.spanNames = p
+	}
+}
+
+// WithSpanNameFormatter names the spans with the names returned by format,
+// e.g. "elasticsearch search orders-*", instead of the default "es/<method>"
+// and "es/<endpoint>" ones. An empty name keeps the default one. The names
+// are still capped and sanitized by the span name policy.
+func WithSpanNameFormatter(format func(req *http.Request) string) TraceOpt {
+	return func(r *Transport) {
+		r.spanNameFormatter = format
+	}
+}
+
+// defaultOperationPrefix is the prefix of the span names.
+const defaultOperationPrefix = "es/"
+
+// withOperationPrefix replaces the default prefix of a span name with the
+// given one.
+func withOperationPrefix(name, prefix string) string {
+	if prefix == defaultOperationPrefix || !strings.HasPrefix(name, defaultOperationPrefix) {
+		return name
+	}
+	return prefix + name[len(defaultOperationPrefix):]
+}
+
+// spanName returns a span name with the operation prefix of the transport.
+func (r *Transport) spanName(name string) string {
+	return withOperationPrefix(name, r.operationPrefix)
+}
+
+// WithOperationPrefix replaces the "es/" prefix of the span names, e.g. with
+// "os/" to tell apart the calls to OpenSearch clusters. The names returned by
+// the span name formatter are prefixed as well when they start with "es/".
+// The spans started by StartOperation keep the default prefix.
+func WithOperationPrefix(prefix string) TraceOpt {
+	return func(r *Transport) {
+		r.operationPrefix = prefix
+	}
+}
@@ -0,0 +1,182 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestIndexTagAndSpanName(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		opts         []TraceOpt
+		path         string
+		expectedName string
+		expectedTag  string
+	}{
+		{nil, "/logs,metrics/_search", "es/_search", "logs,metrics"},
+		{[]TraceOpt{WithIndexInSpanName()}, "/logs,metrics/_search", "es/_search logs,metrics", "logs,metrics"},
+		{[]TraceOpt{WithIndexInSpanName(), WithCanonicalSpanNames()}, "/logs/_search", "es/search logs", "logs"},
+		{[]TraceOpt{WithIndexInSpanName()}, "/_cluster/health", "es/cluster.health", ""},
+	}
+
+	for _, tc := range testCases {
+		transport := NewTransport(tracer, tc.opts...)
+		req, _ := http.NewRequest("GET", srv.URL+tc.path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		spans := reporter.Flush()
+		if want, have := 1, len(spans); want != have {
+			t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+		}
+
+		if want, have := tc.expectedName, spans[0].Name; want != have {
+			t.Errorf("unexpected span name for %s; want %q, have %q", tc.path, want, have)
+		}
+
+		if want, have := tc.expectedTag, spans[0].Tags["es.index"]; want != have {
+			t.Errorf("unexpected index for %s; want %q, have %q", tc.path, want, have)
+		}
+	}
+}
+
+func TestSanitizeSpanName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"es/_search logs-2024.01.01,metrics", "es/_search logs-2024.01.01,metrics"},
+		{"es/_search logs?q=user:1", "es/_search logs"},
+		{"es/_doc 1234567", "es/_doc {id}"},
+		{"es/_doc 3f2504e0-4f89-11d3-9a0c-0305e82c3301", "es/_doc {id}"},
+		{"es/_doc logs,aBcD3fGhIjKlMnOp1", "es/_doc logs,{id}"},
+		{"es/_doc 2024", "es/_doc 2024"},
+	}
+
+	for _, tc := range testCases {
+		if want, have := tc.expected, SanitizeSpanName(tc.name); want != have {
+			t.Errorf("unexpected sanitized name for %q; want %q, have %q", tc.name, want, have)
+		}
+	}
+}
+
+func TestSpanNamePolicy(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	indices := strings.Repeat("logs-ñ,", 40)
+	testCases := []struct {
+		opts         []TraceOpt
+		expectedName string
+	}{
+		{[]TraceOpt{WithIndexInSpanName()}, ("es/_search " + indices)[:128]},
+		{[]TraceOpt{WithIndexInSpanName(), WithSpanNamePolicy(SpanNamePolicy{MaxLength: 17})}, "es/_search logs-"},
+		{[]TraceOpt{WithIndexInSpanName(), WithSpanNamePolicy(SpanNamePolicy{})}, "es/_search " + indices},
+	}
+
+	for _, tc := range testCases {
+		transport := NewTransport(tracer, tc.opts...)
+		req, _ := http.NewRequest("GET", srv.URL+"/"+indices+"/_search", nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		spans := reporter.Flush()
+		if want, have := tc.expectedName, spans[0].Name; want != have {
+			t.Errorf("unexpected span name; want %q, have %q", want, have)
+		}
+	}
+}
+
+func TestSpanNameFormatter(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithSpanNameFormatter(func(req *http.Request) string {
+		if !strings.HasSuffix(req.URL.Path, "/_search") {
+			return ""
+		}
+		return "elasticsearch search " + strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/"), "/_search")
+	}))
+	for _, path := range []string{"/orders-*/_search", "/orders/_refresh"} {
+		req, _ := http.NewRequest("POST", srv.URL+path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "elasticsearch search orders-*", spans[0].Name; want != have {
+		t.Errorf("unexpected formatted span name; want %q, have %q", want, have)
+	}
+
+	if want, have := "es/_refresh", spans[1].Name; want != have {
+		t.Errorf("unexpected default span name; want %q, have %q", want, have)
+	}
+}
+
+func TestOperationPrefix(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithOperationPrefix("os/"))
+	for _, r := range []struct{ method, path string }{
+		{"POST", "/orders/_search"},
+		{"PUT", "/orders/_myplugin"},
+	} {
+		req, _ := http.NewRequest(r.method, srv.URL+r.path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for i, name := range []string{"os/_search", "os/PUT"} {
+		if want, have := name, spans[i].Name; want != have {
+			t.Errorf("unexpected span name; want %q, have %q", want, have)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package zipkines
+
+import (
+	"context"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// The keys of the main tags recorded by the transport, to be referenced by
+// dashboards and tests.
+const (
+	// TagESQuery holds the request body, see WithTagQuery.
+	TagESQuery zipkin.Tag = "es.query"
+	// TagESHitsTotal holds the total hits of a search, see WithTagTotalHits.
+	TagESHitsTotal zipkin.Tag = "es.hits.total"
+	// TagESHitsTotalRelation holds whether the total hits is accurate ("eq")
+	// or a lower bound ("gte").
+	TagESHitsTotalRelation zipkin.Tag = "es.hits.total.relation"
+	// TagESShardsTotal holds the number of shards a request hit, see
+	// WithTagTotalShards.
+	TagESShardsTotal zipkin.Tag = "es.shards.total"
+	// TagESQueryString holds the sanitized query string, see
+	// WithTagRawQueryString.
+	TagESQueryString zipkin.Tag = "es.query_string"
+	// TagESIndex holds the index expression targeted by a request.
+	TagESIndex zipkin.Tag = "es.index"
+	// TagESOperation holds the canonical name of the operation of a request.
+	TagESOperation zipkin.Tag = "es.operation"
+	// TagESHost holds the host a request was sent to, see WithTagHost.
+	TagESHost zipkin.Tag = "es.host"
+)
+
+// TagESQueryParamsPrefix prefixes the keys of the whitelisted query params
+// tags, e.g. "es.query_params.routing".
+const TagESQueryParamsPrefix = "es.query_params."
+
+// WithDefaultTags tags every span with the given static tags, e.g. the
+// cluster alias or the environment. The tags recorded by the transport take
+// precedence over them.
+func WithDefaultTags(tags map[string]string) TraceOpt {
+	return func(r *Transport) {
+		r.defaultTags = make(map[string]string, len(tags))
+		for key, val := range tags {
+			r.defaultTags[key] = val
+		}
+	}
+}
+
+type tagsKey struct{}
+
+// ContextWithTags returns a context whose ES calls are tagged with the given
+// tags, e.g. the tenant or the search feature, on top of the ones of the
+// parent context. They take precedence over the default tags.
+func ContextWithTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string, len(tags))
+	for key, val := range tagsFromContext(ctx) {
+		merged[key] = val
+	}
+	for key, val := range tags {
+		merged[key] = val
+	}
+	return context.WithValue(ctx, tagsKey{}, merged)
+}
+
+func tagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(tagsKey{}).(map[string]string)
+	return tags
+}
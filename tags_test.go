@@ -0,0 +1,119 @@
+package zipkines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestTagKeys(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"_shards":{"total":3},"hits":{"total":{"value":12,"relation":"eq"}}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(
+		tracer,
+		WithTagQuery(),
+		WithTagTotalHits(),
+		WithTagTotalShards(),
+		WithWhitelistQueryParams("routing"),
+	)
+	req, _ := http.NewRequest("POST", srv.URL+"/orders/_search?routing=eu", strings.NewReader(`{"size":1}`))
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for key, val := range map[string]string{
+		string(TagESQuery):                 `{"size":1}`,
+		string(TagESHitsTotal):             "12",
+		string(TagESHitsTotalRelation):     "eq",
+		string(TagESShardsTotal):           "3",
+		string(TagESIndex):                 "orders",
+		string(TagESOperation):             "search",
+		TagESQueryParamsPrefix + "routing": "eu",
+	} {
+		if want, have := val, spans[0].Tags[key]; want != have {
+			t.Errorf("unexpected %q tag; want %q, have %q", key, want, have)
+		}
+	}
+}
+
+func TestDefaultTags(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tags := map[string]string{"es.cluster.alias": "eu-west", "env": "prod"}
+	transport := NewTransport(tracer, WithDefaultTags(tags))
+	tags["env"] = "staging"
+
+	req, _ := http.NewRequest("GET", srv.URL+"/orders/_search", nil)
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for key, val := range map[string]string{"es.cluster.alias": "eu-west", "env": "prod"} {
+		if want, have := val, spans[0].Tags[key]; want != have {
+			t.Errorf("unexpected %q tag; want %q, have %q", key, want, have)
+		}
+	}
+}
+
+func TestContextWithTags(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithDefaultTags(map[string]string{"tenant": "none", "env": "prod"}))
+
+	ctx := ContextWithTags(context.Background(), map[string]string{"tenant": "acme", "feature": "autocomplete"})
+	ctx = ContextWithTags(ctx, map[string]string{"feature": "search"})
+	req, _ := http.NewRequest("GET", srv.URL+"/orders/_search", nil)
+	res, err := transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for key, val := range map[string]string{"tenant": "acme", "feature": "search", "env": "prod"} {
+		if want, have := val, spans[0].Tags[key]; want != have {
+			t.Errorf("unexpected %q tag; want %q, have %q", key, want, have)
+		}
+	}
+}
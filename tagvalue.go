@@ -0,0 +1,45 @@
+package zipkines
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// safeTagValue returns the value made safe to be tagged from content found
+// in the bodies: invalid UTF-8 sequences are replaced by U+FFFD and control
+// characters, including new lines, are escaped as `\uXXXX` so they never get
+// raw into the tag. If max is greater than zero the value is truncated to at
+// most max bytes without splitting a rune or an escape sequence.
+func safeTagValue(s string, max int) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+
+		var piece string
+		switch {
+		case r == utf8.RuneError && size == 1:
+			piece = string(utf8.RuneError)
+		case r < 0x20 || (r >= 0x7f && r < 0xa0):
+			piece = fmt.Sprintf(`\u%04x`, r)
+		default:
+			piece = string(r)
+		}
+
+		if max > 0 && b.Len()+len(piece) > max {
+			return b.String()
+		}
+		b.WriteString(piece)
+	}
+	return b.String()
+}
+
+// WithMaxTagValueLength truncates the values tagged from the bodies, e.g. the
+// query, to at most n bytes. Values are cut at rune boundaries so they remain
+// valid UTF-8.
+func WithMaxTagValueLength(n int) TraceOpt {
+	return func(r *Transport) {
+		r.opts.MaxTagValueLength = n
+	}
+}
@@ -0,0 +1,67 @@
+package zipkines
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestSafeTagValue(t *testing.T) {
+	testCases := []struct {
+		value    string
+		max      int
+		expected string
+	}{
+		{`{"query":"ñandú"}`, 0, `{"query":"ñandú"}`},
+		{"a\xffb", 0, "a�b"},
+		{"a\x00b\nc", 0, `a\u0000b\u000ac`},
+		{"a\u0085b", 0, `a\u0085b`},
+		{"ñandú", 3, "ña"},
+		{"ñandú", 2, "ñ"},
+		{"a\x00", 3, "a"},
+	}
+
+	for _, tc := range testCases {
+		have := safeTagValue(tc.value, tc.max)
+		if want := tc.expected; want != have {
+			t.Errorf("unexpected value for %q; want %q, have %q", tc.value, want, have)
+		}
+		if !utf8.ValidString(have) {
+			t.Errorf("unexpected invalid UTF-8 value for %q: %q", tc.value, have)
+		}
+	}
+}
+
+func TestQueryTagIsSafe(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagQuery(), WithMaxTagValueLength(14))
+	body := "{\"q\":\"\x01ñ\xfe\"}"
+	req, _ := http.NewRequest("POST", srv.URL+"/logs/_search", bytes.NewBufferString(body))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := `{"q":"\u0001ñ`, spans[0].Tags["es.query"]; want != have {
+		t.Errorf("unexpected query; want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,43 @@
+package zipkines
+
+import (
+	"net/http"
+	"regexp"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// breakerName extracts the name of the tripped circuit breaker from the
+// reason of a circuit breaking exception, e.g. "parent" from "[parent] Data
+// too large, data for [<http_request>] would be [...]".
+var breakerName = regexp.MustCompile(`^\[([^\]]+)\]`)
+
+// tagThrottled tags a 429 response as "es.throttled" along with the
+// Retry-After header, if any, as "es.retry_after", so the capacity problems
+// stand out from the application bugs.
+func tagThrottled(span zipkin.Span, res *http.Response) {
+	span.Tag("es.throttled", "true")
+	if retryAfter := res.Header.Get("Retry-After"); retryAfter != "" {
+		span.Tag("es.retry_after", retryAfter)
+	}
+}
+
+// tagThrottlingCause tags the type of the exception behind a 429 as
+// "es.throttled.cause", e.g. "es_rejected_execution_exception", and for the
+// circuit breaking exceptions the name of the breaker as "es.breaker".
+func tagThrottlingCause(span zipkin.Span, resErr errorResponse) {
+	causes := append([]errorCause{resErr.errorCause}, resErr.RootCause...)
+	for _, cause := range causes {
+		if cause.Type == "" {
+			continue
+		}
+
+		span.Tag("es.throttled.cause", cause.Type)
+		if cause.Type == "circuit_breaking_exception" {
+			if m := breakerName.FindStringSubmatch(cause.Reason); m != nil {
+				span.Tag("es.breaker", m[1])
+			}
+		}
+		return
+	}
+}
@@ -0,0 +1,53 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestThrottlingIsTagged(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Retry-After", "30")
+		rw.WriteHeader(http.StatusTooManyRequests)
+		rw.Write([]byte(`{"error":{"root_cause":[{"type":"circuit_breaking_exception",` +
+			`"reason":"[parent] Data too large, data for [<http_request>] would be [123/1gb]"}],` +
+			`"type":"circuit_breaking_exception","reason":"[parent] Data too large, data for [<http_request>] would be [123/1gb]"},"status":429}`))
+	}))
+	defer srv.Close()
+
+	for _, opts := range [][]TraceOpt{nil, {WithTagErrorType()}} {
+		transport := NewTransport(tracer, opts...)
+		req, _ := http.NewRequest("GET", srv.URL+"/orders/_search", nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for i, errTag := range []string{"429", "circuit_breaking_exception"} {
+		for key, val := range map[string]string{
+			"error":              errTag,
+			"es.throttled":       "true",
+			"es.retry_after":     "30",
+			"es.throttled.cause": "circuit_breaking_exception",
+			"es.breaker":         "parent",
+		} {
+			if want, have := val, spans[i].Tags[key]; want != have {
+				t.Errorf("unexpected %q tag for span %d; want %q, have %q", key, i, want, have)
+			}
+		}
+	}
+}
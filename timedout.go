@@ -0,0 +1,36 @@
+package zipkines
+
+import (
+	"encoding/json"
+	"fmt"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+type timedOutResponse struct {
+	TimedOut *bool `json:"timed_out"`
+}
+
+// tagTimedOut tags whether a response timed out, if it tells, and tags the
+// timed out ones as errors.
+func tagTimedOut(span zipkin.Span, body []byte) {
+	res := timedOutResponse{}
+	if err := json.Unmarshal(body, &res); err != nil || res.TimedOut == nil {
+		return
+	}
+
+	span.Tag("es.timed_out", fmt.Sprintf("%t", *res.TimedOut))
+	if *res.TimedOut {
+		zipkin.TagError.Set(span, "timed_out")
+	}
+}
+
+// WithTagTimedOut tags "es.timed_out" for the successful responses telling
+// whether they timed out, e.g. searches, and tags the timed out ones as
+// errors as ES returns the partial results gathered before the timeout with
+// a 200.
+func WithTagTimedOut() TraceOpt {
+	return func(r *Transport) {
+		r.opts.TagTimedOut = true
+	}
+}
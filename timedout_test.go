@@ -0,0 +1,49 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestTagTimedOut(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/slow/_search" {
+			rw.Write([]byte(`{"took":1000,"timed_out":true,"hits":{"total":3}}`))
+			return
+		}
+		rw.Write([]byte(`{"took":10,"timed_out":false,"hits":{"total":3}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagTimedOut())
+	for _, path := range []string{"/slow/_search", "/fast/_search"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expectedTags := []map[string]string{
+		{"es.timed_out": "true", "error": "timed_out"},
+		{"es.timed_out": "false", "error": ""},
+	}
+	for i, tags := range expectedTags {
+		for key, val := range tags {
+			if want, have := val, spans[i].Tags[key]; want != have {
+				t.Errorf("unexpected %q tag for span %d; want %q, have %q", key, i, want, have)
+			}
+		}
+	}
+}
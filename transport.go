@@ -1,18 +1,22 @@
 package zipkines
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"os"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	zipkin "github.com/openzipkin/zipkin-go"
 	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type successHitsNShardsResponse struct {
@@ -22,101 +26,648 @@ type successHitsNShardsResponse struct {
 
 type successHitsResponse struct {
 	Hits struct {
-		Total int `json:"total"`
+		Total hitsTotal `json:"total"`
 	} `json:"hits"`
 }
 
+// hitsTotal is the total hits of a search, either a number as in ES 6 and
+// earlier or an object as in ES 7+, e.g. `{"value":274,"relation":"eq"}`.
+type hitsTotal struct {
+	Value int
+	// Relation is "eq" for an accurate value or "gte" for a lower bound, it
+	// is empty for the legacy format.
+	Relation string
+}
+
+func (t *hitsTotal) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		obj := struct {
+			Value    int    `json:"value"`
+			Relation string `json:"relation"`
+		}{}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		t.Value, t.Relation = obj.Value, obj.Relation
+		return nil
+	}
+
+	if string(data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(data, &t.Value); err != nil {
+		return err
+	}
+	t.Relation = ""
+	return nil
+}
+
+// tagHitsTotal tags the total hits and their relation, if any.
+func tagHitsTotal(span zipkin.Span, total hitsTotal) {
+	if total.Value > 0 {
+		TagESHitsTotal.Set(span, fmt.Sprintf("%d", total.Value))
+	}
+	if total.Relation != "" {
+		TagESHitsTotalRelation.Set(span, total.Relation)
+	}
+}
+
 type successShardsResponse struct {
-	Shards struct {
-		Total int `json:"total"`
-	} `json:"_shards"`
+	Shards shardsCounts `json:"_shards"`
 }
 
-type errorResponse struct {
-	Type string `json:"type"`
+type shardsCounts struct {
+	Total      int `json:"total"`
+	Successful int `json:"successful"`
+	Skipped    int `json:"skipped"`
+	Failed     int `json:"failed"`
+	Failures   []struct {
+		Index  string     `json:"index"`
+		Reason errorCause `json:"reason"`
+	} `json:"failures"`
 }
 
+// maxShardFailures is the maximum number of shard failures tagged.
+const maxShardFailures = 3
+
+// tagShards tags the number of shards a request hit, along with how many of
+// them succeeded, were skipped and failed. Partial failures tag the span as
+// an error as the response misses their results, the first failures are
+// tagged as "es.shards.failure.<n>.type" and "es.shards.failure.<n>.reason"
+// plus their index. The reasons are redacted according to the document ID
+// policy.
+func tagShards(span zipkin.Span, shards shardsCounts, ids DocIDPolicy) {
+	if shards.Total == 0 {
+		return
+	}
+
+	TagESShardsTotal.Set(span, fmt.Sprintf("%d", shards.Total))
+	span.Tag("es.shards.successful", fmt.Sprintf("%d", shards.Successful))
+	span.Tag("es.shards.skipped", fmt.Sprintf("%d", shards.Skipped))
+	span.Tag("es.shards.failed", fmt.Sprintf("%d", shards.Failed))
+	if shards.Failed > 0 {
+		zipkin.TagError.Set(span, fmt.Sprintf("%d of %d shards failed", shards.Failed, shards.Total))
+	}
+
+	for i, failure := range shards.Failures {
+		if i == maxShardFailures {
+			break
+		}
+		prefix := fmt.Sprintf("es.shards.failure.%d.", i+1)
+		if failure.Index != "" {
+			span.Tag(prefix+"index", failure.Index)
+		}
+		if failure.Reason.Type != "" {
+			span.Tag(prefix+"type", failure.Reason.Type)
+		}
+		if failure.Reason.Reason != "" {
+			span.Tag(prefix+"reason", safeTagValue(ids.redactReason(failure.Reason.Reason), maxErrorReasonLength))
+		}
+	}
+}
+
+// TraceOpts holds the tagging options of a transport. They can be inspected
+// through Transport.Options, e.g. to log which tagging features are active.
 type TraceOpts struct {
-	whitelistQueryParams []string
-	tagQuery             bool
-	tagErrorType         bool
-	tagTotalHits         bool
-	tagTotalShards       bool
+	// WhitelistQueryParams are the query params tagged as
+	// "es.query_params.<param>".
+	WhitelistQueryParams []string
+	// OperationQueryParams are the query params tagged as
+	// "es.query_params.<param>" for the requests to the operation they are
+	// keyed by, on top of WhitelistQueryParams.
+	OperationQueryParams map[string][]string
+	// RawQueryParams tags the raw value of the opaque query params, e.g.
+	// "scroll_id", instead of a hash.
+	RawQueryParams bool
+	// TagRawQueryString tags the whole sanitized query string.
+	TagRawQueryString bool
+	// TagQuery tags the query sent in non GET requests. Bulk requests get
+	// their action counts tagged instead.
+	TagQuery bool
+	// TagErrorType tags the error type of non successful responses.
+	TagErrorType bool
+	// TagTotalHits tags the total hits of successful responses, or the count
+	// of the count responses.
+	TagTotalHits bool
+	// TagTotalShards tags the total shards of successful responses.
+	TagTotalShards bool
+	// TagTook tags the time ES took to process successful requests.
+	TagTook bool
+	// TagTimedOut tags whether the successful requests timed out.
+	TagTimedOut bool
+	// TagHost tags the Host header and uses it as remote service name unless
+	// one is given.
+	TagHost bool
+	// TagUnsampled enables the body derived tagging for unsampled spans.
+	TagUnsampled bool
+	// TagProfileNodes tags the nodes serving the profiled searches.
+	TagProfileNodes bool
+	// OmitAPIDetails leaves out the details specific to the APIs parsed from
+	// the bodies, e.g. the bulk item failures, the cluster health or the
+	// leader index of the follow requests.
+	OmitAPIDetails bool
+	// NodeHeaders are the response headers tagged as "es.node.<header>".
+	NodeHeaders []string
+	// ResponsePointerRules tag values pointed in the responses.
+	ResponsePointerRules []ResponsePointerRule
+	// AnnotateDestructiveWildcards annotates wildcard index deletions.
+	AnnotateDestructiveWildcards bool
+	// UnredactedPainlessParams disables the redaction of the painless
+	// script params.
+	UnredactedPainlessParams bool
+	// DocIDPolicy defines how document IDs are tagged.
+	DocIDPolicy DocIDPolicy
+	// CanonicalSpanNames names the spans after the canonical operations.
+	CanonicalSpanNames bool
+	// MaxTagValueLength is the maximum length in bytes of the values tagged
+	// from the bodies, zero means unlimited.
+	MaxTagValueLength int
+}
+
+// withoutBodyTagging returns the options with all the body derived tagging
+// disabled.
+func (o TraceOpts) withoutBodyTagging() TraceOpts {
+	o.TagQuery = false
+	o.TagErrorType = false
+	o.TagTotalHits = false
+	o.TagTotalShards = false
+	o.TagTook = false
+	o.TagTimedOut = false
+	o.ResponsePointerRules = nil
+	o.TagProfileNodes = false
+	return o
 }
 
-type transport struct {
-	parent http.RoundTripper
-	tracer *zipkin.Tracer
-	logger *log.Logger
-	opts   TraceOpts
+// opaqueQueryParams are the query params known to hold huge opaque values
+// which are hashed rather than tagged when whitelisted.
+var opaqueQueryParams = map[string]bool{
+	"scroll_id": true,
 }
 
-func (r *transport) RoundTrip(req *http.Request) (*http.Response, error) {
-	span, _ := r.tracer.StartSpanFromContext(req.Context(), "es/"+req.Method, zipkin.Kind(model.Client))
-	if span == nil {
+// Transport is a http.RoundTripper tracing the calls made to ES.
+//
+// A Transport is safe for concurrent use by multiple goroutines, hence a
+// single instance is meant to be shared by all the clients talking to a
+// cluster. Its configuration can not be changed once NewTransport returns
+// and every request works on its own copy of the tagging options. Internal
+// state shared across requests, e.g. the index rollup, is guarded by locks.
+// Functions passed through options, e.g. the clock, are called concurrently
+// and must be safe for concurrent use as well.
+type Transport struct {
+	// skippedBodyReads is first to be 64-bit aligned for the atomic
+	// operations on 32-bit platforms.
+	skippedBodyReads uint64
+
+	parent    http.RoundTripper
+	tracer    *zipkin.Tracer
+	logger    *log.Logger
+	opts      TraceOpts
+	rollup    *indexRollup
+	histogram *latencyHistogram
+	now       func() time.Time
+
+	maxChunkedRead int64
+	maxBodyRead    int64
+	replay         ReplaySink
+
+	additionalReporter reporter.Reporter
+
+	maxRedirects int
+
+	spanScopedLogging bool
+	clusterStatus     atomic.Value
+	aliases           atomic.Value
+
+	disabledFamilies map[APIFamily]bool
+	untaggedFamilies map[APIFamily]bool
+
+	remoteServiceName string
+	tagNodeURL        bool
+	indexInSpanName   bool
+	spanNameFormatter func(req *http.Request) string
+	warningSink       func(Warning)
+	finishOnBodyClose bool
+	spanNames         SpanNamePolicy
+	operationPrefix   string
+	client            *http.Client
+	defaultTags       map[string]string
+	errorPolicy       ErrorPolicy
+	opaqueID          opaqueIDMode
+	traceContext      bool
+	b3                B3Style
+	bridge            BridgeTracer
+	metrics           *metrics
+	metricsRegisterer prometheus.Registerer
+	metricsErr        error
+
+	lazyResponseParsing bool
+	serverSlowThreshold time.Duration
+	querySanitizer      QuerySanitizer
+	spanCustomizer      SpanCustomizer
+	filter              func(*http.Request) bool
+	tagSamplingDecision bool
+	classifier          OperationClassifier
+
+	connectionAnnotations bool
+	msearchChildSpans     bool
+	shadowTimeout         time.Duration
+
+	fingerprint *fingerprinter
+	ledger      *traceLedger
+	codecs      map[string]Codec
+}
+
+func (r *Transport) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	traced, tagged, readable := r.familyTracing(req)
+	if !traced || r.skipsTracing(req) {
 		return r.parent.RoundTrip(req)
 	}
-	defer span.Finish()
+
+	parentSC, hasParent := spanParent(req.Context())
+	if hasParent && !r.admitSpan(parentSC) {
+		return r.overflowRoundTrip(req, parentSC)
+	}
+
+	// spans which might be held by the N+1 detection are finished by the
+	// ledger.
+	holdable := hasParent && r.ledger != nil && r.ledger.nPlusOne > 0
+
+	// the spans use the wall clock, regardless of WithClock.
+	spanStart := time.Now()
+
+	name := r.spanName("es/" + req.Method)
+	var span zipkin.Span = r.startClientSpan(req.Context(), name, parentSC, hasParent, spanStart)
+	span = &nameRecorder{Span: span, name: name, policy: r.spanNames, prefix: r.operationPrefix}
+	var held *queryBurst
+	// stopConnectionTrace is set when the connection milestones are
+	// annotated.
+	stopConnectionTrace := func() {}
+	finish := func() {
+		stopConnectionTrace()
+		if held != nil {
+			held.requestDone(time.Since(spanStart))
+			return
+		}
+		span.Finish()
+	}
+	// onBodyRead is set when the response body is parsed as the caller
+	// reads it, instead of by the transport.
+	var onBodyRead func()
+	defer func() {
+		if r.spanCustomizer != nil {
+			r.spanCustomizer(span, req, res, err)
+		}
+		if (r.finishOnBodyClose || onBodyRead != nil) && err == nil && res != nil && res.Body != nil && res.Body != http.NoBody {
+			res.Body = &finishingBody{ReadCloser: res.Body, size: res.ContentLength, finish: func(aborted bool) {
+				if aborted {
+					span.Tag("es.response.aborted", "true")
+				} else if onBodyRead != nil {
+					onBodyRead()
+				}
+				finish()
+			}}
+			return
+		}
+		finish()
+	}()
+
+	var logger printfLogger = r.requestLogger(span)
+	if r.warningSink != nil {
+		logger = warningLogger{logger, func(message string) {
+			r.warn(WarningParse, message, req, span)
+		}}
+	}
+	if r.tagSamplingDecision {
+		r.tagSampled(span)
+	}
+
+	opts := r.opts
+	if v, ok := verbosityFromContext(req.Context()); ok {
+		opts = v.apply(opts)
+	}
+	if !tagged {
+		opts = VerbosityMinimal.apply(opts).withoutBodyTagging()
+	}
+
+	// spans which won't be reported are not worth the cost of reading and
+	// parsing the bodies, and the bodies of the security APIs are off limits.
+	tagBodies := readable && (opts.TagUnsampled || isSampled(span))
+	if !tagBodies {
+		opts = opts.withoutBodyTagging()
+	}
+	detailed := tagBodies && !opts.OmitAPIDetails
 
 	zipkin.TagHTTPMethod.Set(span, req.Method)
-	zipkin.TagHTTPPath.Set(span, req.URL.Path)
+	zipkin.TagHTTPPath.Set(span, opts.DocIDPolicy.redactPath(req.URL.Path))
+	index := indexFromPath(req.URL.Path)
+	if index != "" {
+		TagESIndex.Set(span, index)
+	}
+	tagFanOut(req.Context(), span)
+	if isShadowFromContext(req.Context()) {
+		span.Tag("es.shadow", "true")
+	}
+
+	if status := r.polledClusterStatus(); status != "" {
+		span.Tag("es.cluster.polled_status", status)
+	}
 
-	if len(r.opts.whitelistQueryParams) > 0 {
+	if deadline, ok := req.Context().Deadline(); ok {
+		// a negative value means the request was doomed from the beginning
+		span.Tag("es.deadline.remaining_ms", fmt.Sprintf("%d", deadline.Sub(r.now()).Milliseconds()))
+	}
+
+	if opts.TagHost && req.Host != "" {
+		TagESHost.Set(span, req.Host)
+	}
+	span.SetRemoteEndpoint(r.remoteEndpoint(req, opts.TagHost))
+	if r.tagNodeURL {
+		span.Tag("es.node.url", req.URL.Scheme+"://"+req.URL.Host)
+	}
+
+	endpoint, isKnownOperation := r.classify(req.Method, req.URL.Path)
+	operation := endpoint.Operation
+	whitelist := opts.WhitelistQueryParams
+	if params := opts.OperationQueryParams[operation]; isKnownOperation && len(params) > 0 {
+		// the whitelist is shared by the concurrent requests
+		whitelist = append(append([]string(nil), whitelist...), params...)
+	}
+	if len(whitelist) > 0 {
 		params := req.URL.Query()
-		for _, key := range r.opts.whitelistQueryParams {
+		for _, key := range whitelist {
+			if isCredentialQueryParam(key) {
+				// credentials are never tagged, even if whitelisted.
+				continue
+			}
 			if val := params.Get(key); val != "" {
-				span.Tag("es.query_params."+key, val)
+				if opaqueQueryParams[key] && !opts.RawQueryParams {
+					val = fmt.Sprintf("%s (len %d)", shortHash(val), len(val))
+				}
+				span.Tag(TagESQueryParamsPrefix+key, val)
 			}
 		}
 	}
 
-	if req.Method == "GET" || req.Method == "POST" {
-		pieces := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
-		if pieces[0] == "_tasks" {
-			span.SetName("es/_tasks")
-		} else if len(pieces) > 0 && pieces[len(pieces)-1][:1] == "_" {
-			span.SetName("es/" + pieces[len(pieces)-1])
+	if opts.TagRawQueryString && req.URL.RawQuery != "" {
+		if qs := sanitizedQueryString(req.URL.RawQuery, opts.RawQueryParams); qs != "" {
+			TagESQueryString.Set(span, safeTagValue(qs, opts.MaxTagValueLength))
 		}
 	}
 
-	if r.opts.tagQuery && req.Method != "GET" && req.Body != nil {
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			r.logger.Printf("failed to read the request body to tag the query: %v", err)
-			io.Copy(ioutil.Discard, req.Body)
-			return nil, err
+	if isKnownOperation {
+		TagESOperation.Set(span, operation)
+		if opts.CanonicalSpanNames {
+			span.SetName("es/" + operation)
+		} else {
+			span.SetName(defaultSpanName(operation))
+		}
+	}
+
+	r.tagAlias(span, req.URL.Path, endpoint.Operation)
+	painless := endpoint.Operation == "scripts_painless_execute"
+	scroll := endpoint.Operation == "scroll" || endpoint.Operation == "clear_scroll"
+	ccr, isCCR := ccrCallOf(endpoint)
+
+	tagMaintenance(span, req, endpoint)
+	tagIngestManagement(span, endpoint)
+	tagAsyncSearchRequest(span, endpoint)
+	if isCCR {
+		ccr.tag(span)
+	}
+	if endpoint.Operation == "scroll" {
+		countScrollPage(req.Context())
+	}
+	if req.Method == "DELETE" {
+		tagIndicesDeletion(span, req.URL.Path, opts.AnnotateDestructiveWildcards)
+	}
+
+	if named, ok := span.(*nameRecorder); ok && r.indexInSpanName && index != "" {
+		span.SetName(named.name + " " + index)
+	}
+
+	if r.spanNameFormatter != nil {
+		if name := r.spanNameFormatter(req); name != "" {
+			span.SetName(name)
+		}
+	}
+
+	replay := r.replay != nil && isSampled(span)
+	msearch := r.msearchChildSpans && tagBodies && endpoint.Operation == "msearch"
+	var msearchTargets []string
+	// the scroll ID is only looked up in the body if not in the URL
+	scrollInBody := detailed && scroll && scrollID(req, nil) == ""
+	if scroll && !scrollInBody {
+		tagScrollID(span, scrollID(req, nil))
+	}
+	readsBody := (opts.TagQuery && req.Method != "GET") || (detailed && painless) || (detailed && isCCR && ccr.readsBody()) || replay || r.fingerprint != nil || holdable || msearch || scrollInBody
+	tagRequestBody := func(body []byte) {
+		if scrollInBody {
+			tagScrollID(span, scrollID(req, body))
+		}
+		if msearch && len(body) > 0 {
+			var err error
+			if msearchTargets, err = msearchIndices(body, index); err != nil {
+				logger.Printf("failed to parse the msearch request body: %v", err)
+			}
+		}
+
+		query := body
+		if isCCR && detailed {
+			tagCCRFollowBody(span, body)
+		} else if painless {
+			var scriptContext string
+			// the params are redacted from the query whatever the details.
+			scriptContext, query = parsePainlessExecute(body, !opts.UnredactedPainlessParams)
+			if scriptContext != "" && detailed {
+				span.Tag("es.painless.context", safeTagValue(scriptContext, opts.MaxTagValueLength))
+			}
 		}
-		defer req.Body.Close()
-		req.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 
-		if len(body) > 0 {
-			span.Tag("es.query", string(body))
+		if opts.TagQuery && len(query) > 0 && endpoint.Operation == "bulk" {
+			// the raw payload is huge and the documents are useless as tags
+			if err := tagBulkActions(span, query); err != nil {
+				logger.Printf("failed to parse the bulk request body to tag the actions: %v", err)
+			}
+		} else if opts.TagQuery && len(query) > 0 {
+			if val, ok := r.queryTagValue(opts, req.Method, req.URL.Path, query); ok {
+				TagESQuery.Set(span, val)
+			}
+		}
+
+		if (r.fingerprint != nil || holdable) && len(query) > 0 {
+			docs, ok := r.decodeBody(req.Header.Get("Content-Type"), query)
+			var fingerprint string
+			if ok {
+				fingerprint, ok = r.queryFingerprinter().sumDocs(docs)
+			}
+			if ok {
+				if r.fingerprint != nil {
+					span.Tag("es.query.fingerprint", fingerprint)
+				}
+				if holdable {
+					held = r.ledger.recordQuery(parentSC.TraceID, fingerprint, span)
+				}
+			}
+		}
+
+		if replay && len(query) > 0 {
+			r.replay(ReplayRecord{
+				TraceID: span.Context().TraceID.String(),
+				SpanID:  span.Context().ID.String(),
+				Method:  req.Method,
+				Path:    opts.DocIDPolicy.redactPath(req.URL.Path),
+				Params:  withoutCredentialParams(req.URL.RawQuery),
+				Index:   indexFromPath(req.URL.Path),
+				Body:    string(opts.DocIDPolicy.redactBody(query)),
+			})
+		}
+	}
+
+	var continueTap *requestTap
+	if tagBodies && readsBody && req.Body != nil && req.Body != http.NoBody {
+		if expectsContinue(req) {
+			// reading the body upfront would defeat waiting for ES to accept
+			// it, it is tapped as the parent transport sends it instead.
+			req, continueTap = r.tapRequestBody(req)
+		} else {
+			var body []byte
+			var err error
+			// body is nil when it is too big to be tagged
+			req, body, err = r.readRequestBody(req)
+			if err != nil {
+				logger.Printf("failed to read the request body to tag the query: %v", err)
+				req.Body.Close()
+				return nil, err
+			}
+			tagRequestBody(body)
 		}
 	}
 
-	res, rtErr := r.parent.RoundTrip(req)
+	if r.connectionAnnotations {
+		var trace *httptrace.ClientTrace
+		trace, stopConnectionTrace = connectionTrace(span)
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	}
+	req = r.injectHeaders(req, span.Context())
+
+	start := r.now()
+	var rtErr error
+	res, rtErr = r.parent.RoundTrip(req)
+	if rtErr == nil && r.maxRedirects > 0 && isRedirect(res.StatusCode) {
+		var redirects int
+		res, redirects, rtErr = r.followRedirects(req, res)
+		span.Tag("es.redirects", fmt.Sprintf("%d", redirects))
+	}
+	end := r.now()
+	if continueTap != nil {
+		if body, ok := continueTap.sent(); ok {
+			tagRequestBody(body)
+		}
+	}
+	if r.rollup != nil {
+		failed := rtErr != nil || res.StatusCode >= 400
+		if wStart, stats := r.rollup.record(end, indexFromPath(req.URL.Path), end.Sub(start), failed); stats != nil {
+			r.emitRollup(wStart, end, stats)
+		}
+	}
+	r.recordLatency(req, end, end.Sub(start))
+	r.recordMetrics(req, res, rtErr, end.Sub(start))
 	if rtErr != nil {
 		zipkin.TagError.Set(span, rtErr.Error())
 		return nil, rtErr
 	}
 	zipkin.TagHTTPStatusCode.Set(span, fmt.Sprintf("%d", res.StatusCode))
+	// the negotiated protocol, e.g. "HTTP/2.0" when the parent transport
+	// multiplexes requests over HTTP/2 (including h2c to proxies).
+	span.Tag("es.http.proto", res.Proto)
+	tagNodeHeaders(span, opts.NodeHeaders, res)
+	r.warnResponse(req, res, span)
+
+	if painless {
+		span.Tag("es.painless.success", fmt.Sprintf("%t", res.StatusCode >= 200 && res.StatusCode <= 299))
+	}
+
+	if res.StatusCode >= 300 && res.StatusCode <= 399 {
+		// redirect bodies are not worth parsing, if any they are likely HTML.
+		tagRedirect(span, res)
+		return res, nil
+	}
+
+	if req.Method == "HEAD" {
+		// HEAD responses carry no body, the outcome is in the status code.
+		span.Tag("es.response.empty", "true")
+		switch {
+		case res.StatusCode >= 200 && res.StatusCode <= 299:
+			span.Tag("es.exists", "true")
+		case res.StatusCode == 404:
+			span.Tag("es.exists", "false")
+		}
+		if (res.StatusCode < 200 || res.StatusCode > 299) && r.errorPolicy(req, res.StatusCode) {
+			zipkin.TagError.Set(span, fmt.Sprintf("%d", res.StatusCode))
+		}
+		return res, nil
+	}
+
+	if isEmptyResponse(res) {
+		// e.g. refresh or forcemerge behind some proxies, there is nothing
+		// to read nor parse.
+		span.Tag("es.response.empty", "true")
+		if (res.StatusCode < 200 || res.StatusCode > 299) && r.errorPolicy(req, res.StatusCode) {
+			zipkin.TagError.Set(span, fmt.Sprintf("%d", res.StatusCode))
+		}
+		return res, nil
+	}
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		if r.opts.tagErrorType {
-			resBody, err := ioutil.ReadAll(res.Body)
-			if err != nil {
-				r.logger.Printf("failed to read the response body to tag the error: %v", err)
-				io.Copy(ioutil.Discard, res.Body)
-				return nil, err
-			}
-			defer res.Body.Close()
+		if !r.errorPolicy(req, res.StatusCode) {
+			return res, nil
+		}
 
-			resErr := errorResponse{}
-			if err := json.Unmarshal(resBody, &resErr); err != nil {
-				return nil, err
-			}
-			zipkin.TagError.Set(span, resErr.Type)
-			res.Body = ioutil.NopCloser(bytes.NewBuffer(resBody))
+		throttled := res.StatusCode == http.StatusTooManyRequests
+		if throttled {
+			tagThrottled(span, res)
+		}
+
+		// the body of the throttled responses tells which breaker tripped.
+		if !readable || !(opts.TagErrorType || (throttled && detailed)) {
+			zipkin.TagError.Set(span, fmt.Sprintf("%d", res.StatusCode))
+			return res, rtErr
+		}
+
+		resBody, complete, err := r.readResponseBody(res)
+		if err != nil {
+			logger.Printf("failed to read the response body to tag the error: %v", err)
+			zipkin.TagError.Set(span, fmt.Sprintf("%d", res.StatusCode))
+			return res, rtErr
+		}
+
+		if complete && len(resBody) > 0 {
+			resBody, complete = r.uncompressedBody(res, resBody)
+		}
+
+		if !complete || len(resBody) == 0 {
+			zipkin.TagError.Set(span, fmt.Sprintf("%d", res.StatusCode))
+			return res, nil
+		}
+
+		// non JSON bodies, e.g. the HTML pages of the proxies, are still
+		// handed to the caller.
+		errBody, _, err := r.jsonBody(res.Header.Get("Content-Type"), resBody)
+		var resErr errorResponse
+		if err == nil {
+			resErr, err = parseErrorResponse(errBody)
+		}
+		if err != nil {
+			logger.Printf("failed to parse the response body to tag the error: %v", err)
+			zipkin.TagError.Set(span, fmt.Sprintf("%d", res.StatusCode))
+			return res, nil
+		}
+
+		if throttled {
+			tagThrottlingCause(span, resErr)
+		}
+		if opts.TagErrorType {
+			tagErrorResponse(span, res, resErr, opts.MaxTagValueLength, opts.DocIDPolicy)
 		} else {
 			zipkin.TagError.Set(span, fmt.Sprintf("%d", res.StatusCode))
 		}
@@ -124,112 +675,471 @@ func (r *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		return res, rtErr
 	}
 
-	var resBody []byte
-	var err error
-	if r.opts.tagTotalHits || r.opts.tagTotalShards {
-		resBody, err = ioutil.ReadAll(res.Body)
-		if err != nil {
-			r.logger.Printf("failed to read the response body to tag the response values: %v", err)
-			io.Copy(ioutil.Discard, res.Body)
-			return nil, err
+	var pointerRules []ResponsePointerRule
+	if readable {
+		pointerRules = matchingPointerRules(opts.ResponsePointerRules, req.URL.Path)
+	}
+	if detailed && isCCR && ccr.isStats() {
+		pointerRules = append(pointerRules, ccrStatsRule)
+	}
+
+	st := successTagging{
+		opts:         opts,
+		pointerRules: pointerRules,
+		meta:         ResponseMetaFromContext(req.Context()),
+		// the bulk responses are successful even if all the items failed.
+		bulkErrors:     detailed && endpoint.Operation == "bulk",
+		byQuery:        detailed && isByQuery(endpoint),
+		asyncSearch:    detailed && isAsyncSearchResponse(endpoint),
+		clusterHealth:  detailed && endpoint.Operation == "cluster.health",
+		count:          endpoint.Operation == "count",
+		cat:            isCat(endpoint),
+		catRows:        detailed,
+		msearch:        msearch,
+		scrollOpen:     detailed && !scroll && req.URL.Query().Get("scroll") != "",
+		msearchTargets: msearchTargets,
+		spanStart:      spanStart,
+		shardWarnings:  r.warningSink != nil && readable,
+		serverSlow:     r.serverSlowThreshold > 0 && readable,
+	}
+	if !st.readsBody() {
+		return res, nil
+	}
+
+	if r.lazyResponseParsing {
+		tap := &tappedBody{ReadCloser: res.Body, limit: r.bodyReadLimit(-1)}
+		res.Body = tap
+		onBodyRead = func() {
+			resBody, complete := tap.body()
+			if !complete {
+				r.skipBodyRead()
+				return
+			}
+			if len(resBody) == 0 {
+				span.Tag("es.response.empty", "true")
+				return
+			}
+			if resBody, complete = r.uncompressedBody(res, resBody); !complete {
+				return
+			}
+			r.tagSuccessBody(span, req, res, resBody, st, logger)
 		}
-		defer res.Body.Close()
-		res.Body = ioutil.NopCloser(bytes.NewBuffer(resBody))
+		return res, nil
 	}
 
-	if r.opts.tagTotalHits && r.opts.tagTotalShards {
-		sRes := successHitsNShardsResponse{}
-		if err := json.Unmarshal(resBody, &sRes); err != nil {
-			return res, err
+	resBody, complete, err := r.readResponseBody(res)
+	if err != nil {
+		logger.Printf("failed to read the response body to tag the response values: %v", err)
+		return res, nil
+	}
+
+	if !complete {
+		return res, nil
+	}
+
+	if len(resBody) == 0 {
+		// chunked responses do not announce their emptiness.
+		span.Tag("es.response.empty", "true")
+		return res, nil
+	}
+
+	if resBody, complete = r.uncompressedBody(res, resBody); !complete {
+		return res, nil
+	}
+
+	r.tagSuccessBody(span, req, res, resBody, st, logger)
+	return res, nil
+}
+
+// successTagging holds what is extracted from a successful response body.
+type successTagging struct {
+	opts           TraceOpts
+	pointerRules   []ResponsePointerRule
+	meta           *ResponseMeta
+	bulkErrors     bool
+	byQuery        bool
+	asyncSearch    bool
+	clusterHealth  bool
+	count          bool
+	cat            bool
+	catRows        bool
+	msearch        bool
+	msearchTargets []string
+	scrollOpen     bool
+	spanStart      time.Time
+	shardWarnings  bool
+	serverSlow     bool
+}
+
+func (st successTagging) readsBody() bool {
+	if st.cat {
+		// the _cat responses are not worth parsing but for their rows.
+		return st.catRows
+	}
+	return st.opts.TagTotalHits || st.opts.TagTotalShards || st.opts.TagTook || st.opts.TagTimedOut || len(st.pointerRules) > 0 || st.opts.TagProfileNodes ||
+		st.meta != nil || st.bulkErrors || st.byQuery || st.asyncSearch || st.clusterHealth || st.msearch || st.scrollOpen || st.shardWarnings || st.serverSlow
+}
+
+// tagSuccessBody extracts the tags from a successful response body, decoded
+// by the codec of its content type, logging the parse failures. Text bodies,
+// e.g. the ones of `_nodes/hot_threads` or `_sql?format=txt`, are not parsed
+// but for the `_cat` rows.
+func (r *Transport) tagSuccessBody(
+	span zipkin.Span,
+	req *http.Request,
+	res *http.Response,
+	rawBody []byte,
+	st successTagging,
+	logger printfLogger,
+) {
+	resBody, text, err := r.jsonBody(res.Header.Get("Content-Type"), rawBody)
+	if st.cat {
+		// the formats without a codec, e.g. yaml, are not parsed.
+		if err == nil {
+			if err := tagCatRows(span, req, res, resBody); err != nil {
+				logger.Printf("failed to parse the response body to tag the cat rows: %v", err)
+			}
+		}
+		return
+	}
+
+	if err != nil {
+		logger.Printf("failed to decode the response body to tag the response values: %v", err)
+		return
+	}
+	if text {
+		return
+	}
+
+	opts := st.opts
+	if st.meta != nil {
+		if err := st.meta.fill(resBody); err != nil {
+			logger.Printf("failed to parse the response body to hand the response values: %v", err)
+		}
+	}
+
+	if st.shardWarnings {
+		r.warnShardFailures(req, resBody, span)
+	}
+
+	if opts.TagTook {
+		tagTook(span, resBody)
+	}
+
+	if opts.TagTimedOut {
+		tagTimedOut(span, resBody)
+	}
+
+	if st.serverSlow {
+		r.checkServerSlow(span, resBody)
+	}
+
+	if st.bulkErrors {
+		if err := tagBulkErrors(span, resBody, opts.TagErrorType); err != nil {
+			logger.Printf("failed to parse the response body to tag the bulk errors: %v", err)
 		}
+	}
 
-		if sRes.Shards.Total > 0 {
-			span.Tag("es.shards.total", fmt.Sprintf("%d", sRes.Shards.Total))
+	if st.scrollOpen {
+		tagOpenedScrollID(span, resBody)
+	}
+
+	if st.msearch {
+		if err := r.emitMSearchSpans(span, st.msearchTargets, resBody, st.spanStart); err != nil {
+			logger.Printf("failed to parse the response body to emit the msearch spans: %v", err)
 		}
-		if sRes.Hits.Total > 0 {
-			span.Tag("es.hits.total", fmt.Sprintf("%d", sRes.Hits.Total))
+	}
+
+	if st.byQuery {
+		if err := tagByQuery(span, resBody); err != nil {
+			logger.Printf("failed to parse the response body to tag the by query counters: %v", err)
 		}
-	} else if r.opts.tagTotalHits {
-		sRes := successHitsResponse{}
-		if err := json.Unmarshal(resBody, &sRes); err != nil {
-			return res, err
+	}
+
+	if st.asyncSearch {
+		if err := tagAsyncSearchResponse(span, resBody); err != nil {
+			logger.Printf("failed to parse the response body to tag the async search: %v", err)
+		}
+	}
+
+	if st.clusterHealth {
+		if err := tagClusterHealth(span, resBody); err != nil {
+			logger.Printf("failed to parse the response body to tag the cluster health: %v", err)
 		}
+	}
 
-		if sRes.Hits.Total > 0 {
-			span.Tag("es.hits.total", fmt.Sprintf("%d", sRes.Hits.Total))
+	if opts.TagProfileNodes {
+		if err := tagProfileNodes(span, resBody); err != nil {
+			logger.Printf("failed to parse the response body to tag the profile nodes: %v", err)
+		}
+	}
+
+	if opts.TagTotalHits && opts.TagTotalShards {
+		sRes := successHitsNShardsResponse{}
+		if err := json.Unmarshal(resBody, &sRes); err != nil {
+			logger.Printf("failed to parse the response body to tag the hits and shards: %v", err)
+		} else {
+			tagShards(span, sRes.Shards, opts.DocIDPolicy)
+			tagHitsTotal(span, sRes.Hits.Total)
 		}
-	} else if r.opts.tagTotalShards {
+	} else if opts.TagTotalHits {
+		sRes := successHitsResponse{}
+		if err := json.Unmarshal(resBody, &sRes); err != nil {
+			logger.Printf("failed to parse the response body to tag the hits: %v", err)
+		} else {
+			tagHitsTotal(span, sRes.Hits.Total)
+		}
+	} else if opts.TagTotalShards {
 		sRes := successShardsResponse{}
 		if err := json.Unmarshal(resBody, &sRes); err != nil {
-			return res, err
+			logger.Printf("failed to parse the response body to tag the shards: %v", err)
+		} else {
+			tagShards(span, sRes.Shards, opts.DocIDPolicy)
 		}
+	}
 
-		if sRes.Shards.Total > 0 {
-			span.Tag("es.shards.total", fmt.Sprintf("%d", sRes.Shards.Total))
+	if opts.TagTotalHits && st.count {
+		if err := tagCount(span, resBody); err != nil {
+			logger.Printf("failed to parse the response body to tag the count: %v", err)
 		}
 	}
 
-	return res, nil
+	if len(st.pointerRules) > 0 {
+		if docs, ok := r.decodeBody(res.Header.Get("Content-Type"), rawBody); !ok {
+			logger.Printf("failed to decode the %q response body to tag the pointed values", res.Header.Get("Content-Type"))
+		} else if err := tagResponsePointers(span, docs, st.pointerRules); err != nil {
+			logger.Printf("failed to parse the response body to tag the pointed values: %v", err)
+		}
+	}
 }
 
-type TraceOpt func(r *transport)
+// indexFromPath returns the index expression targeted by an ES path or an
+// empty string when the path addresses a cluster level API.
+func indexFromPath(path string) string {
+	pieces := strings.SplitN(strings.Trim(path, "/"), "/", 2)
+	if pieces[0] == "" || pieces[0][:1] == "_" {
+		return ""
+	}
+	return pieces[0]
+}
+
+// isEmptyResponse tells whether the response announces it has no body.
+func isEmptyResponse(res *http.Response) bool {
+	return res.StatusCode == http.StatusNoContent || res.ContentLength == 0
+}
+
+// isSampled tells whether the span is going to be reported.
+func isSampled(span zipkin.Span) bool {
+	sc := span.Context()
+	return sc.Debug || (sc.Sampled != nil && *sc.Sampled)
+}
+
+// hostWithoutPort strips the port, if any, from a host.
+func hostWithoutPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+type TraceOpt func(r *Transport)
 
 // RoundTripper allows to inject a `http.RoundTripper` to be wrapped but it should
 // never be used with a traced transport, otherwise traces will be duplicated.
 func RoundTripper(rt http.RoundTripper) TraceOpt {
-	return func(r *transport) {
+	return func(r *Transport) {
 		r.parent = rt
 	}
 }
 
 // WithLogger allows to pass a `log.Logger` into the transport
 func WithLogger(l *log.Logger) TraceOpt {
-	return func(r *transport) {
+	return func(r *Transport) {
 		r.logger = l
 	}
 }
 
 // WithWhitelistQueryParams allows to pass the whitelist of query parameters
-// that should be recorded in a ES query, e.g. "_routing"
+// that should be recorded in a ES query, e.g. "_routing". The params which
+// might carry credentials, e.g. "api_key", are never recorded.
 func WithWhitelistQueryParams(l ...string) TraceOpt {
-	return func(r *transport) {
-		r.opts.whitelistQueryParams = l
+	return func(r *Transport) {
+		r.opts.WhitelistQueryParams = l
+	}
+}
+
+// WithOperationQueryParams whitelists query params only for the requests to
+// the given operation, as named in "es.operation", e.g. "scroll_id" for
+// "scroll" or "routing" for "index", keeping the tags small and relevant.
+func WithOperationQueryParams(operation string, params ...string) TraceOpt {
+	return func(r *Transport) {
+		if r.opts.OperationQueryParams == nil {
+			r.opts.OperationQueryParams = map[string][]string{}
+		}
+		r.opts.OperationQueryParams[operation] = params
+	}
+}
+
+// WithUnsafeRawQueryParams tags the raw value of the whitelisted query params
+// known to hold huge opaque values, e.g. "scroll_id", which are otherwise
+// tagged as a short hash plus their length.
+func WithUnsafeRawQueryParams() TraceOpt {
+	return func(r *Transport) {
+		r.opts.RawQueryParams = true
 	}
 }
 
-// WithTagQuery tags the query sent to ES in non GET requests.
+// WithTagQuery tags the query sent to ES in non GET requests. For the bulk
+// requests the number of actions per type, e.g. "es.bulk.actions.index", and
+// the number of documents, "es.bulk.docs", are tagged instead of the payload.
+// The bodies of the requests expecting a 100-continue are not read upfront
+// but as they are sent, hence they are only tagged if ES accepted them. The
+// bodies of the security APIs are never tagged.
 func WithTagQuery() TraceOpt {
-	return func(r *transport) {
-		r.opts.tagQuery = true
+	return func(r *Transport) {
+		r.opts.TagQuery = true
 	}
 }
 
-// WithTagTotalHits tags the total hits in a successful query response.
+// WithTagErrorType tags the error type returned by ES in non successful
+// responses instead of the status code, along with the truncated reason and
+// the first root cause. The security APIs responses are still
+// tagged with the status code as their bodies might echo credentials.
+func WithTagErrorType() TraceOpt {
+	return func(r *Transport) {
+		r.opts.TagErrorType = true
+	}
+}
+
+// WithTagHost tags the Host header of the request, which might differ from
+// the URL host when clusters are routed by it behind a single load balancer,
+// and uses it as the remote service name.
+func WithTagHost() TraceOpt {
+	return func(r *Transport) {
+		r.opts.TagHost = true
+	}
+}
+
+// WithTagUnsampled enables the body derived tagging (query, error type,
+// hits, shards, etc.) also for the spans which are not sampled. By default
+// the bodies are not read nor parsed for them as they are never reported.
+func WithTagUnsampled() TraceOpt {
+	return func(r *Transport) {
+		r.opts.TagUnsampled = true
+	}
+}
+
+// WithTagTotalHits tags the total hits in a successful query response, or
+// the number of matching documents of a count response as "es.count".
 func WithTagTotalHits() TraceOpt {
-	return func(r *transport) {
-		r.opts.tagTotalHits = true
+	return func(r *Transport) {
+		r.opts.TagTotalHits = true
 	}
 }
 
 // WithTagTotalShards tags the total shards being queried in a successful
-// query response.
+// query response, along with the successful, skipped and failed ones. The
+// span is tagged as an error if some shards failed.
 func WithTagTotalShards() TraceOpt {
-	return func(r *transport) {
-		r.opts.tagTotalShards = true
+	return func(r *Transport) {
+		r.opts.TagTotalShards = true
+	}
+}
+
+// WithTagTook tags the time ES reports it took to process a successful
+// request, e.g. a search or a bulk, as "es.took_ms" so it can be compared to
+// the span duration.
+func WithTagTook() TraceOpt {
+	return func(r *Transport) {
+		r.opts.TagTook = true
+	}
+}
+
+// WithClock allows to inject the source of time used by the transport for its
+// own duration based decisions, e.g. closing the index rollup windows. It does
+// not affect the timestamps and durations of the request spans, including the
+// msearch child spans, but the spans of WithIndexRollup and
+// WithLatencyHistogram cover the windows measured by it.
+func WithClock(now func() time.Time) TraceOpt {
+	return func(r *Transport) {
+		r.now = now
+	}
+}
+
+// Options returns a copy of the effective tagging options of the transport.
+func (r *Transport) Options() TraceOpts {
+	opts := r.opts
+	opts.WhitelistQueryParams = append([]string(nil), r.opts.WhitelistQueryParams...)
+	if r.opts.OperationQueryParams != nil {
+		opts.OperationQueryParams = make(map[string][]string, len(r.opts.OperationQueryParams))
+		for op, params := range r.opts.OperationQueryParams {
+			opts.OperationQueryParams[op] = append([]string(nil), params...)
+		}
 	}
+	opts.NodeHeaders = append([]string(nil), r.opts.NodeHeaders...)
+	opts.ResponsePointerRules = append([]ResponsePointerRule(nil), r.opts.ResponsePointerRules...)
+	return opts
 }
 
-// NewTransport returns a transport instance including tracing for ES calls
-func NewTransport(tracer *zipkin.Tracer, opts ...TraceOpt) http.RoundTripper {
-	t := &transport{
+// spanParent returns the parent of the spans of the ES calls made with ctx:
+// the span of the operation, see ContextWithOperation, or else the span of
+// ctx.
+func spanParent(ctx context.Context) (model.SpanContext, bool) {
+	if sc, ok := operationFromContext(ctx); ok {
+		return sc, true
+	}
+	if parent := zipkin.SpanFromContext(ctx); parent != nil {
+		return parent.Context(), true
+	}
+	return model.SpanContext{}, false
+}
+
+// startClientSpan starts the span of an ES call, child of parent if any,
+// mirrored and bridged as configured and tagged with the default and the
+// context tags.
+func (r *Transport) startClientSpan(ctx context.Context, name string, parent model.SpanContext, hasParent bool, start time.Time) zipkin.Span {
+	spanOpts := []zipkin.SpanOption{zipkin.Kind(model.Client), zipkin.StartTime(start)}
+	if hasParent {
+		spanOpts = append(spanOpts, zipkin.Parent(parent))
+	}
+
+	var span zipkin.Span = r.tracer.StartSpan(name, spanOpts...)
+	if r.additionalReporter != nil {
+		span = newMirrorSpan(span, r.additionalReporter, name, model.Client, r.tracer.LocalEndpoint(), start)
+	}
+	span = r.bridgeSpan(ctx, span, name)
+	for key, val := range r.defaultTags {
+		span.Tag(key, val)
+	}
+	for key, val := range tagsFromContext(ctx) {
+		span.Tag(key, val)
+	}
+	return span
+}
+
+// NewTransport returns a Transport instance including tracing for ES calls
+func NewTransport(tracer *zipkin.Tracer, opts ...TraceOpt) *Transport {
+	t := &Transport{
 		tracer: tracer,
 		parent: http.DefaultTransport,
 		logger: log.New(os.Stderr, "", log.LstdFlags),
+		now:    time.Now,
+
+		maxChunkedRead:  defaultMaxChunkedRead,
+		spanNames:       DefaultSpanNamePolicy,
+		operationPrefix: defaultOperationPrefix,
+		shadowTimeout:   defaultShadowTimeout,
 	}
+	t.errorPolicy = t.defaultErrorPolicy
 
 	for _, opt := range opts {
 		opt(t)
 	}
 
+	t.registerMetrics()
+	if t.metricsErr != nil {
+		t.logger.Printf("failed to register the metrics: %v", t.metricsErr)
+	}
+
 	return t
 }
@@ -2,15 +2,23 @@ package zipkines
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/openzipkin/zipkin-go"
 	"github.com/openzipkin/zipkin-go/reporter/recorder"
 )
 
+var discardLogger = log.New(ioutil.Discard, "", 0)
+
 func TestRequestSuccess(t *testing.T) {
 	reporter := recorder.NewReporter()
 	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
@@ -51,3 +59,526 @@ func TestRequestSuccess(t *testing.T) {
 		t.Errorf("unexpected spans number; want %d, have %d", want, have)
 	}
 }
+
+func TestHeadExistsRequests(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/my-index" {
+			rw.WriteHeader(200)
+			return
+		}
+		rw.WriteHeader(404)
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagErrorType(), WithTagTotalHits())
+
+	testCases := []struct {
+		path         string
+		expectedName string
+		expectedTag  string
+	}{
+		{"/my-index", "es/index.exists", "true"},
+		{"/my-index/_doc/1", "es/doc.exists", "false"},
+	}
+
+	for _, tc := range testCases {
+		req, _ := http.NewRequest("HEAD", srv.URL+tc.path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		spans := reporter.Flush()
+		if want, have := 1, len(spans); want != have {
+			t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+		}
+
+		if want, have := tc.expectedName, spans[0].Name; want != have {
+			t.Errorf("unexpected span name; want %q, have %q", want, have)
+		}
+
+		if want, have := tc.expectedTag, spans[0].Tags["es.exists"]; want != have {
+			t.Errorf("unexpected exists tag; want %q, have %q", want, have)
+		}
+
+		if _, ok := spans[0].Tags["error"]; ok {
+			t.Errorf("unexpected error tag for %q", tc.path)
+		}
+	}
+}
+
+func TestTagHost(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagHost())
+	req, _ := http.NewRequest("GET", srv.URL+"/_cluster/health", nil)
+	req.Host = "cluster-a.es.internal:9200"
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "cluster-a.es.internal:9200", spans[0].Tags["es.host"]; want != have {
+		t.Errorf("unexpected host tag; want %q, have %q", want, have)
+	}
+
+	if spans[0].RemoteEndpoint == nil {
+		t.Fatal("expected remote endpoint")
+	}
+
+	if want, have := "cluster-a.es.internal", spans[0].RemoteEndpoint.ServiceName; want != have {
+		t.Errorf("unexpected remote service name; want %q, have %q", want, have)
+	}
+}
+
+// TestConcurrentRoundTrips shares a transport with every tagging option
+// enabled among many goroutines, it is meant to be run with -race.
+func TestConcurrentRoundTrips(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-Found-Handling-Instance", "instance-0")
+		if req.URL.Path == "/missing/_search" {
+			rw.WriteHeader(404)
+			rw.Write([]byte(`{"error":{"type":"index_not_found_exception"}}`))
+			return
+		}
+		rw.Write([]byte(`{"took":12,"timed_out":false,"_shards":{"total":6},"hits":{"total":274},` +
+			`"profile":{"shards":[{"id":"[node-1][my-index][0]"}]},"_all":{"primaries":{"docs":{"count":1}}}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(
+		tracer,
+		WithLogger(discardLogger),
+		WithVerbosity(VerbosityVerbose),
+		WithWhitelistQueryParams("routing"),
+		WithOperationQueryParams("search", "preference"),
+		WithUnsafeRawQueryParams(),
+		WithTagQuery(),
+		WithTagRawQueryString(),
+		WithTagErrorType(),
+		WithTagHost(),
+		WithTagUnsampled(),
+		WithTagTotalHits(),
+		WithTagTotalShards(),
+		WithTagTook(),
+		WithTagTimedOut(),
+		WithTagProfileNodes(),
+		WithTagIndexStats(),
+		WithResponsePointerTags("_search", map[string]string{"es.search.took": "/took"}),
+		WithNodeHeaders("X-Found-Handling-Instance"),
+		WithTagSamplingDecision(),
+		WithQueryFingerprint(),
+		WithIndexInSpanName(),
+		WithCanonicalSpanNames(),
+		WithDocIDRedaction(DocIDHash),
+		WithDestructiveWildcardAnnotation(),
+		WithUnredactedPainlessParams(),
+		WithMSearchChildSpans(),
+		WithServerSlowThreshold(time.Millisecond),
+		WithConnectionAnnotations(),
+		WithIndexRollup(0),
+		WithMaxTagValueLength(64),
+		WithDefaultTags(map[string]string{"es.cluster": "test"}),
+		WithLazyResponseParsing(),
+	)
+
+	paths := []string{
+		"/my-index/_search?routing=1&preference=_local",
+		"/missing/_search",
+		"/my-index/_stats",
+		"/my-index/_doc/1",
+		"/_msearch",
+	}
+	const goroutines = 300
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			ctx := ContextWithResponseMeta(context.Background())
+			if i%2 == 0 {
+				ctx = ContextWithVerbosity(ctx, VerbosityMinimal)
+			}
+
+			body := `{"size":25,"profile":true}`
+			if paths[i%len(paths)] == "/_msearch" {
+				body = "{\"index\":\"my-index\"}\n{\"query\":{\"match_all\":{}}}\n"
+			}
+
+			req, _ := http.NewRequest("POST", srv.URL+paths[i%len(paths)], bytes.NewBufferString(body))
+			res, err := transport.RoundTrip(req.WithContext(ctx))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			ioutil.ReadAll(res.Body)
+			res.Body.Close()
+		}(i)
+	}
+	wg.Wait()
+
+	spans := reporter.Flush()
+	if len(spans) < goroutines {
+		t.Errorf("unexpected spans number; want at least %d, have %d", goroutines, len(spans))
+	}
+}
+
+func TestOpaqueQueryParamsAreHashed(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	scrollID := strings.Repeat("DXF1ZXJ5QW5kRmV0Y2gBAAAAAAAAAD4WYm9laVYtZndUQlNsdDcwakFMNjU1QQ", 40)
+	for _, opts := range [][]TraceOpt{
+		{WithWhitelistQueryParams("scroll_id", "scroll")},
+		{WithWhitelistQueryParams("scroll_id", "scroll"), WithUnsafeRawQueryParams()},
+	} {
+		transport := NewTransport(tracer, opts...)
+		req, _ := http.NewRequest("GET", srv.URL+"/_search/scroll?scroll=1m&scroll_id="+scrollID, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expected := fmt.Sprintf("%s (len %d)", shortHash(scrollID), len(scrollID))
+	if want, have := expected, spans[0].Tags["es.query_params.scroll_id"]; want != have {
+		t.Errorf("unexpected scroll_id tag; want %q, have %q", want, have)
+	}
+
+	if want, have := scrollID, spans[1].Tags["es.query_params.scroll_id"]; want != have {
+		t.Errorf("unexpected raw scroll_id tag; want %q, have %q", want, have)
+	}
+
+	if want, have := "1m", spans[0].Tags["es.query_params.scroll"]; want != have {
+		t.Errorf("unexpected scroll tag; want %q, have %q", want, have)
+	}
+}
+
+func TestRemainingDeadlineIsTagged(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	transport := NewTransport(tracer, WithClock(func() time.Time { return now }))
+
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(1500*time.Millisecond))
+	defer cancel()
+
+	req, _ := http.NewRequest("GET", srv.URL+"/_search", nil)
+	if _, err := transport.RoundTrip(req.WithContext(ctx)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "1500", spans[0].Tags["es.deadline.remaining_ms"]; want != have {
+		t.Errorf("unexpected remaining deadline; want %q, have %q", want, have)
+	}
+}
+
+func TestHTTP2ParentTransport(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		reqBody, _ := ioutil.ReadAll(req.Body)
+		if want, have := `{"size":25}`, string(reqBody); want != have {
+			t.Errorf("unexpected query; want %q, have %q", want, have)
+		}
+		rw.Write([]byte(`{"hits":{"total":274}}`))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	transport := NewTransport(tracer, RoundTripper(srv.Client().Transport), WithTagQuery(), WithTagTotalHits())
+
+	// requests are multiplexed over the same connection
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("POST", srv.URL+"/_search", bytes.NewBufferString(`{"size":25}`))
+			res, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			ioutil.ReadAll(res.Body)
+			res.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	spans := reporter.Flush()
+	if want, have := 10, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for _, span := range spans {
+		if want, have := "HTTP/2.0", span.Tags["es.http.proto"]; want != have {
+			t.Errorf("unexpected protocol; want %q, have %q", want, have)
+		}
+		if want, have := "274", span.Tags["es.hits.total"]; want != have {
+			t.Errorf("unexpected hits; want %q, have %q", want, have)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	tracer, _ := zipkin.NewTracer(recorder.NewReporter())
+	transport := NewTransport(tracer, WithVerbosity(VerbosityStandard), WithWhitelistQueryParams("routing"))
+
+	opts := transport.Options()
+	if !opts.TagErrorType || !opts.TagTotalHits || !opts.TagTotalShards || opts.TagQuery {
+		t.Errorf("unexpected tagging options: %+v", opts)
+	}
+
+	opts.WhitelistQueryParams[0] = "modified"
+	if want, have := "routing", transport.Options().WhitelistQueryParams[0]; want != have {
+		t.Errorf("unexpected modification of the transport options; want %q, have %q", want, have)
+	}
+}
+
+func TestEmptyResponses(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/logs/_refresh":
+			rw.WriteHeader(http.StatusNoContent)
+		case "/logs/_forcemerge":
+			// flushing forces a chunked response without a body
+			rw.(http.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagTotalHits(), WithTagTotalShards(), WithTagErrorType(), WithLogger(discardLogger))
+	for _, path := range []string{"/logs/_refresh", "/logs/_forcemerge"} {
+		req, _ := http.NewRequest("POST", srv.URL+path, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error for %s: %v", path, err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for _, span := range spans {
+		if want, have := "true", span.Tags["es.response.empty"]; want != have {
+			t.Errorf("unexpected empty response tag for %s; want %q, have %q", span.Tags["http.path"], want, have)
+		}
+		if want, have := "", span.Tags["error"]; want != have {
+			t.Errorf("unexpected error for %s; want %q, have %q", span.Tags["http.path"], want, have)
+		}
+	}
+}
+
+func TestHitsTotalFormats(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		body     string
+		total    string
+		relation string
+	}{
+		{`{"hits":{"total":274}}`, "274", ""},
+		{`{"hits":{"total":{"value":274,"relation":"eq"}}}`, "274", "eq"},
+		{`{"hits":{"total":{"value":10000,"relation":"gte"}}}`, "10000", "gte"},
+		{`{"hits":{"total":null}}`, "", ""},
+	}
+
+	for _, tc := range testCases {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Write([]byte(tc.body))
+		}))
+
+		for _, transport := range []*Transport{
+			NewTransport(tracer, WithTagTotalHits()),
+			NewTransport(tracer, WithTagTotalHits(), WithTagTotalShards()),
+		} {
+			req, _ := http.NewRequest("GET", srv.URL+"/logs/_search", nil)
+			if _, err := transport.RoundTrip(req); err != nil {
+				t.Fatalf("unexpected error for %s: %v", tc.body, err)
+			}
+
+			spans := reporter.Flush()
+			if want, have := 1, len(spans); want != have {
+				t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+			}
+
+			if want, have := tc.total, spans[0].Tags["es.hits.total"]; want != have {
+				t.Errorf("unexpected total hits for %s; want %q, have %q", tc.body, want, have)
+			}
+
+			if want, have := tc.relation, spans[0].Tags["es.hits.total.relation"]; want != have {
+				t.Errorf("unexpected total hits relation for %s; want %q, have %q", tc.body, want, have)
+			}
+		}
+		srv.Close()
+	}
+}
+
+func TestOperationQueryParams(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(
+		tracer,
+		WithWhitelistQueryParams("timeout"),
+		WithOperationQueryParams("scroll", "scroll"),
+		WithOperationQueryParams("index", "routing"),
+	)
+	for _, url := range []string{
+		"/_search/scroll?scroll=1m&routing=a&timeout=1s",
+		"/logs/_search?scroll=1m&routing=a&timeout=1s",
+	} {
+		req, _ := http.NewRequest("POST", srv.URL+url, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expectedParams := []map[string]string{
+		{"timeout": "1s", "scroll": "1m", "routing": ""},
+		{"timeout": "1s", "scroll": "", "routing": ""},
+	}
+	for i, params := range expectedParams {
+		for key, val := range params {
+			if want, have := val, spans[i].Tags["es.query_params."+key]; want != have {
+				t.Errorf("unexpected %q param for span %d; want %q, have %q", key, i, want, have)
+			}
+		}
+	}
+}
+
+func TestShardCountsAreTagged(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/partial/_search" {
+			rw.Write([]byte(`{"_shards":{"total":5,"successful":3,"skipped":1,"failed":1,"failures":[` +
+				`{"shard":0,"index":"partial","node":"n1","reason":{"type":"query_shard_exception","reason":"No mapping found for [ts]"}}]}}`))
+			return
+		}
+		rw.Write([]byte(`{"_shards":{"total":5,"successful":5,"skipped":2,"failed":0}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithTagTotalShards())
+	for _, path := range []string{"/full/_search", "/partial/_search"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	expectedTags := []map[string]string{
+		{
+			"es.shards.total":      "5",
+			"es.shards.successful": "5",
+			"es.shards.skipped":    "2",
+			"es.shards.failed":     "0",
+			"error":                "",
+		},
+		{
+			"es.shards.successful":       "3",
+			"es.shards.skipped":          "1",
+			"es.shards.failed":           "1",
+			"error":                      "1 of 5 shards failed",
+			"es.shards.failure.1.index":  "partial",
+			"es.shards.failure.1.type":   "query_shard_exception",
+			"es.shards.failure.1.reason": "No mapping found for [ts]",
+			"es.shards.failure.2.type":   "",
+		},
+	}
+	for i, tags := range expectedTags {
+		for key, val := range tags {
+			if want, have := val, spans[i].Tags[key]; want != have {
+				t.Errorf("unexpected %q tag for span %d; want %q, have %q", key, i, want, have)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package zipkines
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateOpts applies the given options to a throwaway transport and reports
+// the invalid values and the combinations which conflict or have no effect,
+// so services can fail fast at startup instead of finding out from their
+// traces. A nil error means the options are consistent.
+func ValidateOpts(opts ...TraceOpt) error {
+	r := &Transport{maxChunkedRead: defaultMaxChunkedRead}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	// the collectors are only registered to spot the conflicts.
+	r.registerMetrics()
+	if r.metrics != nil {
+		r.metrics.unregister(r.metricsRegisterer)
+	}
+
+	if problems := r.optsProblems(); len(problems) > 0 {
+		return fmt.Errorf("invalid tracing options: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (r *Transport) optsProblems() []string {
+	var problems []string
+	add := func(format string, v ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, v...))
+	}
+
+	if r.opts.RawQueryParams && len(r.opts.WhitelistQueryParams) == 0 && len(r.opts.OperationQueryParams) == 0 {
+		add("raw query params have no effect without whitelisted query params")
+	}
+
+	for family := range r.untaggedFamilies {
+		if r.disabledFamilies[family] {
+			add("API family %q is both disabled and untagged", family)
+		}
+	}
+
+	if r.opts.MaxTagValueLength < 0 {
+		add("max tag value length can not be negative, got %d", r.opts.MaxTagValueLength)
+	}
+
+	if r.opts.DocIDPolicy < DocIDKeep || r.opts.DocIDPolicy > DocIDDrop {
+		add("unknown document ID policy %d", r.opts.DocIDPolicy)
+	}
+
+	if r.maxRedirects < 0 {
+		add("max redirects can not be negative, got %d", r.maxRedirects)
+	}
+
+	if r.rollup != nil && r.rollup.interval < 0 {
+		add("index rollup interval can not be negative, got %s", r.rollup.interval)
+	}
+
+	if r.histogram != nil {
+		if r.histogram.interval <= 0 {
+			add("latency histogram interval must be positive, got %s", r.histogram.interval)
+		}
+		if r.histogram.bounds[0] <= 0 {
+			add("latency histogram bounds must be positive, got %s", r.histogram.bounds[0])
+		}
+	}
+
+	if r.ledger != nil {
+		if r.ledger.limit < 0 {
+			add("span budget can not be negative, got %d", r.ledger.limit)
+		}
+		if r.ledger.nPlusOne < 0 || r.ledger.nPlusOne == 1 {
+			add("N+1 detection threshold must be at least 2, got %d", r.ledger.nPlusOne)
+		}
+		if r.ledger.idle <= 0 {
+			add("trace idle timeout must be positive, got %s", r.ledger.idle)
+		}
+	}
+
+	if r.fingerprint != nil {
+		if r.fingerprint.name == "" {
+			add("query fingerprint hash name can not be empty")
+		}
+		if r.fingerprint.newHash == nil {
+			add("query fingerprint hash can not be nil")
+		}
+	}
+
+	for mediaType, c := range r.codecs {
+		if c == nil {
+			add("body codec for %q can not be nil", mediaType)
+		}
+	}
+
+	if r.metricsErr != nil {
+		add("failed to register the metrics: %v", r.metricsErr)
+	}
+
+	return problems
+}
@@ -0,0 +1,81 @@
+package zipkines
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestValidateOpts(t *testing.T) {
+	if err := ValidateOpts(
+		WithTagQuery(),
+		WithWhitelistQueryParams("scroll_id"),
+		WithUnsafeRawQueryParams(),
+		WithSpanBudget(100, time.Second),
+		WithLatencyHistogram(time.Minute),
+		// a zero interval emits a rollup per call
+		WithIndexRollup(0),
+	); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err := ValidateOpts(
+		WithUnsafeRawQueryParams(),
+		WithDisabledAPIFamilies(APIFamilyCat),
+		WithUntaggedAPIFamilies(APIFamilyCat),
+		WithNPlusOneDetection(1, 0),
+		WithQueryFingerprintHash("", nil),
+		WithIndexRollup(-time.Minute),
+	)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	for _, problem := range []string{
+		"raw query params have no effect",
+		`API family "cat" is both disabled and untagged`,
+		"N+1 detection threshold must be at least 2, got 1",
+		"trace idle timeout must be positive",
+		"query fingerprint hash name can not be empty",
+		"query fingerprint hash can not be nil",
+		"index rollup interval can not be negative, got -1m0s",
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected %q in error, have %q", problem, err.Error())
+		}
+	}
+}
+
+func TestValidateOptsReportsConflictingMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "es_client_requests_total",
+		Help: "A gauge taking the name of the requests counter.",
+	}))
+
+	err := ValidateOpts(WithMetrics(registry))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if want, have := "failed to register the metrics", err.Error(); !strings.Contains(have, want) {
+		t.Errorf("unexpected error; want %q in %q", want, have)
+	}
+
+	// validating does not leave collectors behind
+	registry = prometheus.NewRegistry()
+	if err := ValidateOpts(WithMetrics(registry)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mfs, _ := registry.Gather()
+	if want, have := 0, len(mfs); want != have {
+		t.Errorf("unexpected metric families; want %d, have %d", want, have)
+	}
+
+	if err := registry.Register(newMetrics().requests); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
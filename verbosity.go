@@ -0,0 +1,63 @@
+package zipkines
+
+import "context"
+
+// Verbosity is a single knob selecting a predefined set of tags to be
+// recorded in the ES spans.
+type Verbosity int
+
+const (
+	// VerbosityMinimal only records the HTTP method, path, status code and
+	// error as well as the whitelisted query params.
+	VerbosityMinimal Verbosity = iota + 1
+	// VerbosityStandard records the minimal tags plus the error type, the
+	// total hits and shards, the took and timed out values and the API
+	// details parsed from the response, along with the configured node
+	// headers and response pointers.
+	VerbosityStandard
+	// VerbosityVerbose records the standard tags plus the query sent to ES,
+	// the raw query string and the profiled nodes.
+	VerbosityVerbose
+)
+
+// apply sets every per request tagging option of opts according to the
+// verbosity. The node headers and the response pointer rules can not be
+// enabled without being configured, they are only dropped by
+// VerbosityMinimal.
+func (v Verbosity) apply(opts TraceOpts) TraceOpts {
+	opts.TagErrorType = v >= VerbosityStandard
+	opts.TagTotalHits = v >= VerbosityStandard
+	opts.TagTotalShards = v >= VerbosityStandard
+	opts.TagTook = v >= VerbosityStandard
+	opts.TagTimedOut = v >= VerbosityStandard
+	opts.OmitAPIDetails = v < VerbosityStandard
+	opts.TagQuery = v >= VerbosityVerbose
+	opts.TagRawQueryString = v >= VerbosityVerbose
+	opts.TagProfileNodes = v >= VerbosityVerbose
+	if v < VerbosityStandard {
+		opts.NodeHeaders = nil
+		opts.ResponsePointerRules = nil
+	}
+	return opts
+}
+
+// WithVerbosity sets the tags recorded by the transport to the predefined set
+// of the given verbosity. Tagging options passed after this one refine it.
+func WithVerbosity(v Verbosity) TraceOpt {
+	return func(r *Transport) {
+		r.opts = v.apply(r.opts)
+	}
+}
+
+type verbosityKey struct{}
+
+// ContextWithVerbosity returns a context overriding the verbosity of the
+// transport for the requests made with it.
+func ContextWithVerbosity(ctx context.Context, v Verbosity) context.Context {
+	return context.WithValue(ctx, verbosityKey{}, v)
+}
+
+func verbosityFromContext(ctx context.Context) (Verbosity, bool) {
+	v, ok := ctx.Value(verbosityKey{}).(Verbosity)
+	return v, ok
+}
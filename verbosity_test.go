@@ -0,0 +1,177 @@
+package zipkines
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestVerbosityCanBeOverriddenPerRequest(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"_shards":{"total":6},"hits":{"total":274}}`))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer, WithVerbosity(VerbosityVerbose))
+
+	req, _ := http.NewRequest("POST", srv.URL+"/_search", bytes.NewBufferString(`{"size":25}`))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, _ = http.NewRequest("POST", srv.URL+"/_search", bytes.NewBufferString(`{"size":25}`))
+	req = req.WithContext(ContextWithVerbosity(req.Context(), VerbosityMinimal))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	for _, key := range []string{"es.query", "es.hits.total", "es.shards.total"} {
+		if _, ok := spans[0].Tags[key]; !ok {
+			t.Errorf("expected tag %q in verbose span", key)
+		}
+		if _, ok := spans[1].Tags[key]; ok {
+			t.Errorf("unexpected tag %q in minimal span", key)
+		}
+	}
+}
+
+// verbosityExemptOpts are the TraceOpts fields which are not a matter of
+// verbosity, every other field must be set by Verbosity.apply.
+var verbosityExemptOpts = map[string]bool{
+	// the whitelisted query params are recorded at every verbosity.
+	"WhitelistQueryParams": true,
+	"OperationQueryParams": true,
+	"RawQueryParams":       true,
+	// the host is the remote endpoint of the span.
+	"TagHost":                      true,
+	"TagUnsampled":                 true,
+	"AnnotateDestructiveWildcards": true,
+	"UnredactedPainlessParams":     true,
+	"DocIDPolicy":                  true,
+	"CanonicalSpanNames":           true,
+	"MaxTagValueLength":            true,
+}
+
+func TestVerbosityCoversTheTaggingOptions(t *testing.T) {
+	var all TraceOpts
+	v := reflect.ValueOf(&all).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, field := v.Type().Field(i).Name, v.Field(i)
+		if verbosityExemptOpts[name] {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.Bool:
+			field.SetBool(true)
+		case reflect.Slice:
+			field.Set(reflect.MakeSlice(field.Type(), 1, 1))
+		default:
+			t.Fatalf("unexpected tagging option %s, it must be set by Verbosity.apply or be exempted", name)
+		}
+	}
+
+	minimal := reflect.ValueOf(VerbosityMinimal.apply(all))
+	verbose := reflect.ValueOf(VerbosityVerbose.apply(TraceOpts{}))
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if verbosityExemptOpts[name] || name == "OmitAPIDetails" {
+			continue
+		}
+
+		if !minimal.Field(i).IsZero() {
+			t.Errorf("unexpected %s kept by the minimal verbosity", name)
+		}
+
+		if field := verbose.Field(i); field.Kind() == reflect.Bool && !field.Bool() {
+			t.Errorf("expected %s enabled by the verbose verbosity", name)
+		}
+	}
+
+	if !VerbosityMinimal.apply(TraceOpts{}).OmitAPIDetails || VerbosityStandard.apply(all).OmitAPIDetails {
+		t.Errorf("expected the API details to be omitted by the minimal verbosity only")
+	}
+}
+
+func TestMinimalVerbosityContextOmitsBodyTags(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, _ := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+
+	responses := map[string]string{
+		"/_bulk":                  `{"took":3,"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`,
+		"/_cluster/health":        `{"status":"red","number_of_nodes":3,"unassigned_shards":2}`,
+		"/logs/_search":           `{"took":12,"timed_out":false,"_shards":{"total":6,"failed":1},"hits":{"total":274},"profile":{"shards":[{"id":"[node-1][logs][0]"}]}}`,
+		"/_cat/indices":           "green open logs\n",
+		"/logs/_ccr/stats":        `{"indices":[{"shards":[{"operations_written":10}]}]}`,
+		"/_ingest/pipeline/geoip": `{}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-Found-Handling-Instance", "instance-1")
+		if req.URL.Path == "/_cat/indices" {
+			rw.Header().Set("Content-Type", "text/plain")
+		}
+		rw.Write([]byte(responses[req.URL.Path]))
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(
+		tracer,
+		WithVerbosity(VerbosityVerbose),
+		WithTagHost(),
+		WithNodeHeaders("X-Found-Handling-Instance"),
+		WithResponsePointerTags("_search", map[string]string{"es.search.took": "/took"}),
+		WithWhitelistQueryParams("routing"),
+	)
+
+	for path := range responses {
+		req, _ := http.NewRequest("POST", srv.URL+path+"?routing=eu&scroll=1m", bytes.NewBufferString(`{"index":{}}`+"\n{}\n"))
+		req = req.WithContext(ContextWithVerbosity(req.Context(), VerbosityMinimal))
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	spans := reporter.Flush()
+	if want, have := len(responses), len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	allowed := map[string]bool{
+		"http.method":             true,
+		"http.path":               true,
+		"http.status_code":        true,
+		"es.index":                true,
+		"es.operation":            true,
+		"es.host":                 true,
+		"es.query_params.routing": true,
+		"es.http.proto":           true,
+		// the path parameters are not body derived.
+		"es.ccr.follower_index": true,
+		"es.ingest.pipeline":    true,
+	}
+	for _, span := range spans {
+		for key := range span.Tags {
+			if !allowed[key] {
+				t.Errorf("unexpected tag %q in minimal span %s %s", key, span.Name, span.Tags["http.path"])
+			}
+		}
+	}
+}
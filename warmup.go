@@ -0,0 +1,56 @@
+package zipkines
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+)
+
+// Warmup resolves and opens a connection to each of the given hosts, e.g.
+// "https://es-01:9200", by issuing a `HEAD /` request through the parent
+// transport so the connection is kept in its pool for the first actual
+// request. Each host gets a span annotated with the connection milestones.
+// All hosts are attempted and the first error found is returned.
+func (r *Transport) Warmup(ctx context.Context, hosts ...string) error {
+	var firstErr error
+	for _, host := range hosts {
+		if err := r.warmupHost(ctx, host); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (r *Transport) warmupHost(ctx context.Context, host string) error {
+	span, ctx := r.tracer.StartSpanFromContext(ctx, r.spanName("es/warmup"), zipkin.Kind(model.Client))
+	trace, stopConnectionTrace := connectionTrace(span)
+	defer func() {
+		stopConnectionTrace()
+		span.Finish()
+	}()
+
+	req, err := http.NewRequest("HEAD", host+"/", nil)
+	if err != nil {
+		zipkin.TagError.Set(span, err.Error())
+		return err
+	}
+	zipkin.TagHTTPUrl.Set(span, sanitizedURL(req.URL))
+
+	ctx = httptrace.WithClientTrace(ctx, trace)
+	res, err := r.parent.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		zipkin.TagError.Set(span, err.Error())
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	zipkin.TagHTTPStatusCode.Set(span, fmt.Sprintf("%d", res.StatusCode))
+	return nil
+}
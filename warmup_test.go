@@ -0,0 +1,58 @@
+package zipkines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestWarmup(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if want, have := "HEAD", req.Method; want != have {
+			t.Errorf("unexpected method; want %q, have %q", want, have)
+		}
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(tracer)
+	if err := transport.Warmup(context.Background(), srv.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := reporter.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := "es/warmup", spans[0].Name; want != have {
+		t.Errorf("unexpected span name; want %q, have %q", want, have)
+	}
+
+	connected := false
+	for _, a := range spans[0].Annotations {
+		if a.Value == "connect.done" {
+			connected = true
+		}
+	}
+	if !connected {
+		t.Errorf("expected connect.done annotation, have %v", spans[0].Annotations)
+	}
+}
+
+func TestWarmupFailsOnUnreachableHost(t *testing.T) {
+	tracer, _ := zipkin.NewTracer(recorder.NewReporter())
+	transport := NewTransport(tracer)
+	if err := transport.Warmup(context.Background(), "http://127.0.0.1:1"); err == nil {
+		t.Errorf("expected error")
+	}
+}
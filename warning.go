@@ -0,0 +1,112 @@
+package zipkines
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// WarningKind classifies the warnings observed by the transport.
+type WarningKind string
+
+const (
+	// WarningDeprecation is raised for every `Warning` header returned by ES,
+	// e.g. when using a deprecated API or setting.
+	WarningDeprecation WarningKind = "deprecation"
+	// WarningShardFailures is raised for successful responses in which some
+	// shards failed, hence the results are partial.
+	WarningShardFailures WarningKind = "shard_failures"
+	// WarningThrottled is raised for the requests rejected with a 429.
+	WarningThrottled WarningKind = "throttled"
+	// WarningParse is raised when a body can not be read or parsed to tag
+	// it.
+	WarningParse WarningKind = "parse"
+)
+
+// Warning is an anomaly observed by the transport while tracing a request.
+type Warning struct {
+	Kind    WarningKind
+	Message string
+	Method  string
+	// Path is the request path, with its document ID redacted as set by
+	// WithDocIDRedaction.
+	Path string
+	// TraceID and SpanID identify the span of the request.
+	TraceID string
+	SpanID  string
+}
+
+// warn hands a warning to the sink, if any.
+func (r *Transport) warn(kind WarningKind, message string, req *http.Request, span zipkin.Span) {
+	if r.warningSink == nil {
+		return
+	}
+
+	sc := span.Context()
+	r.warningSink(Warning{
+		Kind:    kind,
+		Message: message,
+		Method:  req.Method,
+		Path:    r.opts.DocIDPolicy.redactPath(req.URL.Path),
+		TraceID: sc.TraceID.String(),
+		SpanID:  sc.ID.String(),
+	})
+}
+
+// warnResponse raises the warnings found in the status and headers of a
+// response.
+func (r *Transport) warnResponse(req *http.Request, res *http.Response, span zipkin.Span) {
+	if r.warningSink == nil {
+		return
+	}
+
+	for _, w := range res.Header["Warning"] {
+		r.warn(WarningDeprecation, w, req, span)
+	}
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		r.warn(WarningThrottled, fmt.Sprintf("%d", res.StatusCode), req, span)
+	}
+}
+
+type shardFailuresResponse struct {
+	Shards struct {
+		Total  int `json:"total"`
+		Failed int `json:"failed"`
+	} `json:"_shards"`
+}
+
+// warnShardFailures raises a warning if some shards failed according to a
+// successful response body.
+func (r *Transport) warnShardFailures(req *http.Request, body []byte, span zipkin.Span) {
+	res := shardFailuresResponse{}
+	if err := json.Unmarshal(body, &res); err != nil || res.Shards.Failed == 0 {
+		return
+	}
+	r.warn(WarningShardFailures, fmt.Sprintf("%d of %d shards failed", res.Shards.Failed, res.Shards.Total), req, span)
+}
+
+// warningLogger raises a parse warning for every line logged.
+type warningLogger struct {
+	printfLogger
+	warn func(message string)
+}
+
+func (l warningLogger) Printf(format string, v ...interface{}) {
+	l.printfLogger.Printf(format, v...)
+	l.warn(fmt.Sprintf(format, v...))
+}
+
+// WithWarningSink hands the anomalies observed by the transport, i.e.
+// deprecations, partial shard failures, throttling and body parse failures,
+// to the given function so services can alert on them. It is called
+// synchronously from the requests, regardless of the sampling decision,
+// hence it must be fast and safe for concurrent use. Spotting the partial
+// shard failures requires reading the successful response bodies.
+func WithWarningSink(sink func(Warning)) TraceOpt {
+	return func(r *Transport) {
+		r.warningSink = sink
+	}
+}
@@ -0,0 +1,101 @@
+package zipkines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestWarningSink(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/logs/_search":
+			rw.Header().Add("Warning", `299 Elasticsearch-7.10.0 "[types removal] deprecated"`)
+			rw.Write([]byte(`{"took":1,"_shards":{"total":5,"failed":2},"hits":{"total":0}}`))
+		case "/logs/_doc":
+			rw.WriteHeader(http.StatusTooManyRequests)
+			rw.Write([]byte(`{"error":{"type":"es_rejected_execution_exception"},"status":429}`))
+		default:
+			rw.Write([]byte(`{"took":`))
+		}
+	}))
+	defer srv.Close()
+
+	var warnings []Warning
+	transport := NewTransport(tracer, WithLogger(discardLogger), WithWarningSink(func(w Warning) {
+		warnings = append(warnings, w)
+	}), WithTagErrorType())
+
+	for _, path := range []string{"/logs/_search", "/logs/_doc", "/logs/_bulk"} {
+		req, _ := http.NewRequest("POST", srv.URL+path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	kinds := []WarningKind{WarningDeprecation, WarningShardFailures, WarningThrottled, WarningParse}
+	if want, have := len(kinds), len(warnings); want != have {
+		t.Fatalf("unexpected warnings number; want %d, have %d: %v", want, have, warnings)
+	}
+
+	for i, kind := range kinds {
+		if want, have := kind, warnings[i].Kind; want != have {
+			t.Errorf("unexpected kind for warning %d; want %q, have %q", i, want, have)
+		}
+		if warnings[i].TraceID == "" {
+			t.Errorf("expected trace ID for warning %d", i)
+		}
+	}
+
+	if want, have := "2 of 5 shards failed", warnings[1].Message; want != have {
+		t.Errorf("unexpected message; want %q, have %q", want, have)
+	}
+
+	if want, have := "/logs/_doc", warnings[2].Path; want != have {
+		t.Errorf("unexpected path; want %q, have %q", want, have)
+	}
+}
+
+func TestWarningPathIsRedacted(t *testing.T) {
+	tracer, err := zipkin.NewTracer(recorder.NewReporter(), zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Add("Warning", `299 Elasticsearch-7.10.0 "[types removal] deprecated"`)
+		rw.Write([]byte(`{"found":true}`))
+	}))
+	defer srv.Close()
+
+	var warnings []Warning
+	transport := NewTransport(tracer, WithDocIDRedaction(DocIDDrop), WithWarningSink(func(w Warning) {
+		warnings = append(warnings, w)
+	}))
+
+	req, _ := http.NewRequest("GET", srv.URL+"/users/_doc/jane.doe@example.com", nil)
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	if want, have := 1, len(warnings); want != have {
+		t.Fatalf("unexpected warnings number; want %d, have %d", want, have)
+	}
+
+	if want, have := "/users/_doc/{id}", warnings[0].Path; want != have {
+		t.Errorf("unexpected warning path; want %q, have %q", want, have)
+	}
+}
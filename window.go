@@ -0,0 +1,66 @@
+package zipkines
+
+import (
+	"sync"
+	"time"
+
+	zipkin "github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+)
+
+// window aggregates the calls made during a window of time, e.g. for the
+// index rollup and the latency histogram. The first window starts with the
+// first call and it is closed by the first call made after the interval is
+// over.
+type window struct {
+	mu       sync.Mutex
+	interval time.Duration
+	start    time.Time
+	state    interface{}
+	newState func() interface{}
+}
+
+func newWindow(interval time.Duration, newState func() interface{}) *window {
+	return &window{
+		interval: interval,
+		state:    newState(),
+		newState: newState,
+	}
+}
+
+// record adds a call to the state of the current window through add. If the
+// window is over its start and state are returned so the caller can emit
+// them outside of the lock, otherwise the state is nil.
+func (w *window) record(now time.Time, add func(state interface{})) (time.Time, interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.start.IsZero() {
+		w.start = now
+	}
+
+	add(w.state)
+
+	if now.Sub(w.start) < w.interval {
+		return time.Time{}, nil
+	}
+
+	start, closed := w.start, w.state
+	w.start = now
+	w.state = w.newState()
+	return start, closed
+}
+
+// emitWindowSpan reports a local span covering a closed window, tagged by
+// tag. The window spans are always sampled as they summarize calls whose own
+// spans might have been dropped.
+func (r *Transport) emitWindowSpan(name string, start, end time.Time, tag func(span zipkin.Span)) {
+	sampled := true
+	span := r.tracer.StartSpan(
+		r.spanName(name),
+		zipkin.Parent(model.SpanContext{Sampled: &sampled}),
+		zipkin.StartTime(start),
+	)
+	tag(span)
+	span.FinishedWithDuration(end.Sub(start))
+}
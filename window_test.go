@@ -0,0 +1,36 @@
+package zipkines
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowIsClosedByTheFirstCallAfterTheInterval(t *testing.T) {
+	w := newWindow(time.Minute, func() interface{} { return new(int) })
+	add := func(state interface{}) { *state.(*int)++ }
+
+	start := time.Unix(0, 0)
+	for _, elapsed := range []time.Duration{0, 30 * time.Second, 59 * time.Second} {
+		if _, closed := w.record(start.Add(elapsed), add); closed != nil {
+			t.Fatalf("unexpected closed window after %s", elapsed)
+		}
+	}
+
+	closedStart, closed := w.record(start.Add(time.Minute), add)
+	if closed == nil {
+		t.Fatal("expected the window to be closed")
+	}
+
+	if want, have := start, closedStart; !want.Equal(have) {
+		t.Errorf("unexpected window start; want %s, have %s", want, have)
+	}
+
+	if want, have := 4, *closed.(*int); want != have {
+		t.Errorf("unexpected calls number; want %d, have %d", want, have)
+	}
+
+	// the next window starts with the call closing the previous one
+	if _, closed := w.record(start.Add(time.Minute+59*time.Second), add); closed != nil {
+		t.Error("unexpected closed window")
+	}
+}
@@ -0,0 +1,139 @@
+// Package zipkinestest provides helpers to verify the spans produced by the
+// zipkines transport against real Elasticsearch or OpenSearch servers run in
+// containers through testcontainers.
+package zipkinestest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	zipkines "github.com/jcchavezs/zipkin-instrumentation-go-elasticsearch"
+	zipkin "github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Server describes a containerized search server.
+type Server struct {
+	Image string
+	Env   map[string]string
+}
+
+// Elasticsearch returns a single node Elasticsearch server without security.
+func Elasticsearch(version string) Server {
+	return Server{
+		Image: "docker.elastic.co/elasticsearch/elasticsearch:" + version,
+		Env: map[string]string{
+			"discovery.type":         "single-node",
+			"xpack.security.enabled": "false",
+			"ES_JAVA_OPTS":           "-Xms512m -Xmx512m",
+		},
+	}
+}
+
+// OpenSearch returns a single node OpenSearch server without security.
+func OpenSearch(version string) Server {
+	return Server{
+		Image: "opensearchproject/opensearch:" + version,
+		Env: map[string]string{
+			"discovery.type":          "single-node",
+			"DISABLE_SECURITY_PLUGIN": "true",
+			"OPENSEARCH_JAVA_OPTS":    "-Xms512m -Xmx512m",
+		},
+	}
+}
+
+// Harness runs a server in a container and sends requests to it through a
+// traced transport recording the spans.
+type Harness struct {
+	// URL is the base URL of the server, e.g. "http://localhost:32768".
+	URL       string
+	Reporter  *recorder.ReporterRecorder
+	Transport http.RoundTripper
+
+	container testcontainers.Container
+}
+
+// NewHarness starts the server and waits until it answers requests. The
+// transport is built with the given options. Close must be called to
+// terminate the container.
+func NewHarness(ctx context.Context, srv Server, opts ...zipkines.TraceOpt) (*Harness, error) {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        srv.Image,
+			Env:          srv.Env,
+			ExposedPorts: []string{"9200/tcp"},
+			WaitingFor:   wait.ForHTTP("/_cluster/health?wait_for_status=yellow").WithPort("9200/tcp"),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		container.Terminate(ctx)
+		return nil, err
+	}
+
+	port, err := container.MappedPort(ctx, "9200/tcp")
+	if err != nil {
+		container.Terminate(ctx)
+		return nil, err
+	}
+
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		container.Terminate(ctx)
+		return nil, err
+	}
+
+	return &Harness{
+		URL:       fmt.Sprintf("http://%s:%s", host, port.Port()),
+		Reporter:  reporter,
+		Transport: zipkines.NewTransport(tracer, opts...),
+		container: container,
+	}, nil
+}
+
+// Do sends a request to the server and returns the response status code and
+// the spans recorded meanwhile. An empty body sends no body.
+func (h *Harness) Do(ctx context.Context, method, path, body string) (int, []model.SpanModel, error) {
+	h.Reporter.Flush()
+
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = bytes.NewBufferString(body)
+	}
+
+	req, err := http.NewRequest(method, h.URL+path, reqBody)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if bytes.HasSuffix([]byte(path), []byte("_bulk")) || bytes.HasSuffix([]byte(path), []byte("_msearch")) {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	}
+
+	res, err := h.Transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return 0, h.Reporter.Flush(), err
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	return res.StatusCode, h.Reporter.Flush(), nil
+}
+
+// Close terminates the container.
+func (h *Harness) Close(ctx context.Context) error {
+	return h.container.Terminate(ctx)
+}
@@ -0,0 +1,83 @@
+//go:build integration
+// +build integration
+
+package zipkinestest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	zipkines "github.com/jcchavezs/zipkin-instrumentation-go-elasticsearch"
+)
+
+// The integration tests run with `go test -tags integration ./zipkinestest/`
+// and require a docker daemon. The server versions can be overridden through
+// the ES_VERSION and OPENSEARCH_VERSION environment variables.
+
+type apiCase struct {
+	method       string
+	path         string
+	body         string
+	expectedName string
+	expectedTags map[string]string
+}
+
+var apiMatrix = []apiCase{
+	{"PUT", "/logs", `{"settings":{"number_of_replicas":0}}`, "es/indices.create", nil},
+	{"HEAD", "/logs", "", "es/indices.exists", map[string]string{"es.exists": "true"}},
+	{"PUT", "/logs/_doc/1?refresh=true", `{"message":"hello"}`, "es/index", nil},
+	{"HEAD", "/logs/_doc/1", "", "es/exists", map[string]string{"es.exists": "true"}},
+	{"HEAD", "/logs/_doc/2", "", "es/exists", map[string]string{"es.exists": "false"}},
+	{"GET", "/logs/_doc/1", "", "es/get", nil},
+	{"POST", "/logs/_search", `{"query":{"match_all":{}}}`, "es/search", map[string]string{"es.shards.total": "1"}},
+	{"POST", "/logs/_refresh", "", "es/indices.refresh", map[string]string{"es.index": "logs"}},
+	{"POST", "/logs/_forcemerge?max_num_segments=1", "", "es/indices.forcemerge", map[string]string{"es.forcemerge.max_num_segments": "1"}},
+	{"GET", "/_cluster/health", "", "es/cluster.health", nil},
+	{"DELETE", "/logs", "", "es/indices.delete", nil},
+}
+
+func envOr(key, def string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return def
+}
+
+func runAPIMatrix(t *testing.T, srv Server) {
+	ctx := context.Background()
+	h, err := NewHarness(ctx, srv, zipkines.WithCanonicalSpanNames(), zipkines.WithTagTotalShards())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer h.Close(ctx)
+
+	for _, tc := range apiMatrix {
+		_, spans, err := h.Do(ctx, tc.method, tc.path, tc.body)
+		if err != nil {
+			t.Fatalf("unexpected error for %s %s: %v", tc.method, tc.path, err)
+		}
+
+		if want, have := 1, len(spans); want != have {
+			t.Fatalf("unexpected spans number for %s %s; want %d, have %d", tc.method, tc.path, want, have)
+		}
+
+		if want, have := tc.expectedName, spans[0].Name; want != have {
+			t.Errorf("unexpected span name for %s %s; want %q, have %q", tc.method, tc.path, want, have)
+		}
+
+		for key, val := range tc.expectedTags {
+			if want, have := val, spans[0].Tags[key]; want != have {
+				t.Errorf("unexpected %q tag for %s %s; want %q, have %q", key, tc.method, tc.path, want, have)
+			}
+		}
+	}
+}
+
+func TestElasticsearch(t *testing.T) {
+	runAPIMatrix(t, Elasticsearch(envOr("ES_VERSION", "7.17.10")))
+}
+
+func TestOpenSearch(t *testing.T) {
+	runAPIMatrix(t, OpenSearch(envOr("OPENSEARCH_VERSION", "2.11.0")))
+}
@@ -0,0 +1,58 @@
+// Package zipkinopensearch plugs the zipkines transport into the opensearch-go
+// v2 clients.
+package zipkinopensearch
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+
+	zipkines "github.com/jcchavezs/zipkin-instrumentation-go-elasticsearch"
+	"github.com/opensearch-project/opensearch-go/v2"
+	zipkin "github.com/openzipkin/zipkin-go"
+)
+
+// WrapConfig returns a copy of the config whose transport is traced. The
+// transport of the config, if any, is used as the parent transport. As the
+// client only applies the CA certificate to a plain http.Transport, it is
+// applied here to the parent transport instead.
+func WrapConfig(tracer *zipkin.Tracer, cfg opensearch.Config, opts ...zipkines.TraceOpt) (opensearch.Config, error) {
+	parent := cfg.Transport
+	if parent == nil {
+		parent = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	if len(cfg.CACert) > 0 {
+		t, ok := parent.(*http.Transport)
+		if !ok {
+			return cfg, fmt.Errorf("unable to set the CA certificate for a transport of type %T", parent)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(cfg.CACert) {
+			return cfg, errors.New("unable to add the CA certificate")
+		}
+
+		t = t.Clone()
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.RootCAs = pool
+		parent = t
+		cfg.CACert = nil
+	}
+
+	cfg.Transport = zipkines.NewTransport(tracer, append([]zipkines.TraceOpt{zipkines.RoundTripper(parent)}, opts...)...)
+	return cfg, nil
+}
+
+// NewClient returns an opensearch-go client whose calls are traced.
+func NewClient(tracer *zipkin.Tracer, cfg opensearch.Config, opts ...zipkines.TraceOpt) (*opensearch.Client, error) {
+	cfg, err := WrapConfig(tracer, cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return opensearch.NewClient(cfg)
+}
@@ -0,0 +1,79 @@
+package zipkinopensearch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	zipkines "github.com/jcchavezs/zipkin-instrumentation-go-elasticsearch"
+	"github.com/opensearch-project/opensearch-go/v2"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func TestNewClient(t *testing.T) {
+	reporter := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(reporter, zipkin.WithSampler(zipkin.AlwaysSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"took":1,"hits":{"total":{"value":3,"relation":"eq"}}}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(tracer, opensearch.Config{Addresses: []string{srv.URL}}, zipkines.WithTagQuery())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := client.Search(
+		client.Search.WithContext(context.Background()),
+		client.Search.WithIndex("logs"),
+		client.Search.WithBody(strings.NewReader(`{"query":{"match_all":{}}}`)),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	securityReq, _ := http.NewRequest("PUT", "/_plugins/_security/api/internalusers/alice", strings.NewReader(`{"password":"secret"}`))
+	securityRes, err := client.Perform(securityReq)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	securityRes.Body.Close()
+
+	spans := reporter.Flush()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("unexpected spans number; want %d, have %d", want, have)
+	}
+
+	if want, have := `{"query":{"match_all":{}}}`, spans[0].Tags["es.query"]; want != have {
+		t.Errorf("unexpected query; want %q, have %q", want, have)
+	}
+
+	if want, have := "security.internalusers", spans[1].Tags["es.operation"]; want != have {
+		t.Errorf("unexpected operation; want %q, have %q", want, have)
+	}
+
+	if want, have := "", spans[1].Tags["es.query"]; want != have {
+		t.Errorf("unexpected security body tagged; want %q, have %q", want, have)
+	}
+}
+
+func TestWrapConfigRejectsCACertWithCustomTransport(t *testing.T) {
+	tracer, _ := zipkin.NewTracer(recorder.NewReporter())
+	cfg := opensearch.Config{
+		CACert:    []byte("cert"),
+		Transport: zipkines.NewTransport(tracer),
+	}
+
+	if _, err := WrapConfig(tracer, cfg); err == nil {
+		t.Error("expected an error")
+	}
+}